@@ -0,0 +1,81 @@
+//
+// Copyright 2018-2021 Bryan T. Meyers <root@datadrake.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package cli
+
+import (
+	"fmt"
+	"github.com/DataDrake/cli-ng/v2/cmd"
+	"github.com/DataDrake/eopkg-deps/storage"
+	"os"
+	"os/user"
+)
+
+func init() {
+	cmd.Register(&Claim)
+}
+
+// Claim records that the builds of queued packages have begun, so that "done" can time them
+var Claim = cmd.Sub{
+	Name:  "claim",
+	Alias: "cl",
+	Short: "Mark queued packages as being built, for timing rebuilds, reading stdin if none are listed or with '-f -'",
+	Flags: &ClaimFlags{},
+	Args:  &ClaimArgs{},
+	Run:   ClaimRun,
+}
+
+// ClaimFlags contains the additional flags for the "claim" subcommand
+type ClaimFlags struct {
+	FromFile string `short:"f" long:"from-file" desc:"read package names from a file, one per line, or '-' for stdin"`
+}
+
+// ClaimArgs contains the arguments for the "claim" subcommand
+type ClaimArgs struct {
+	Names []string `zero:"yes" desc:"the names of the packages being built"`
+}
+
+// ClaimRun carries out the "claim" subcommand
+func ClaimRun(r *cmd.Root, c *cmd.Sub) {
+	//flags := r.Flags.(*GlobalFlags)
+	flags := c.Flags.(*ClaimFlags)
+	args := c.Args.(*ClaimArgs)
+	names, err := readNames(args.Names, flags.FromFile)
+	if err != nil {
+		fmt.Printf("Failed to read package names, reason: '%s'\n", err.Error())
+		os.Exit(1)
+	}
+	ctx, stop := interruptible()
+	defer stop()
+	s := storage.NewStore()
+	curr, err := user.Current()
+	if err != nil {
+		fmt.Printf(UserErrorFormat, err.Error())
+		os.Exit(1)
+	}
+	if err = s.OpenContext(ctx, curr.HomeDir+DefaultDBLocation); err != nil {
+		fmt.Printf(DBOpenErrorFormat, err.Error())
+		os.Exit(1)
+	}
+	defer s.Close()
+	if err = s.ClaimToDoContext(ctx, names...); err != nil {
+		fmt.Printf("Failed to claim, reason: '%s'\n", err.Error())
+		os.Exit(exitCode(err))
+	}
+	for _, name := range names {
+		fmt.Printf("Successfully claimed '%s'\n", name)
+	}
+}
@@ -0,0 +1,49 @@
+//
+// Copyright 2018-2021 Bryan T. Meyers <root@datadrake.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package cli
+
+import (
+	"github.com/DataDrake/eopkg-deps/search"
+	"github.com/DataDrake/eopkg-deps/storage"
+)
+
+// componentMatcher matches any of a comma-separated list of components, or everything if empty
+func componentMatcher(patterns string) search.Matcher {
+	var matchers []search.Matcher
+	for _, pattern := range splitNames(patterns) {
+		matchers = append(matchers, search.Component(pattern))
+	}
+	if len(matchers) == 0 {
+		return func(string) bool { return true }
+	}
+	return search.Any(matchers...)
+}
+
+// filterComponents keeps only the packages in a comma-separated list of components
+func filterComponents(pkgs storage.Packages, patterns string) storage.Packages {
+	if len(patterns) == 0 {
+		return pkgs
+	}
+	match := componentMatcher(patterns)
+	kept := make(storage.Packages, 0, len(pkgs))
+	for _, pkg := range pkgs {
+		if match(pkg.Component) {
+			kept = append(kept, pkg)
+		}
+	}
+	return kept
+}
@@ -0,0 +1,122 @@
+//
+// Copyright 2018-2021 Bryan T. Meyers <root@datadrake.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package cli
+
+import (
+	"fmt"
+	"github.com/DataDrake/cli-ng/v2/cmd"
+	"github.com/DataDrake/eopkg-deps/graph"
+	"github.com/DataDrake/eopkg-deps/storage"
+	"os"
+	"os/user"
+	"sort"
+	"text/tabwriter"
+)
+
+func init() {
+	cmd.Register(&Components)
+}
+
+// Components summarizes the dependencies between components
+var Components = cmd.Sub{
+	Name:  "components",
+	Alias: "co",
+	Short: "Get the dependencies between components",
+	Flags: &ComponentsFlags{},
+	Run:   ComponentsRun,
+}
+
+// ComponentsFlags contains the additional flags for the "components" subcommand
+type ComponentsFlags struct {
+	Levels    int    `short:"l" long:"levels" desc:"group components by their first few parts, like 'desktop' for 1 (0 for none)"`
+	Component string `short:"c" long:"component" desc:"only show dependencies from these comma-separated components"`
+}
+
+const (
+	// ComponentsHeader is a table heading for component sizes
+	ComponentsHeader = "Component\tPackages\n"
+	// ComponentsHeaderColor is a table heading for component sizes, in color
+	ComponentsHeaderColor = "\033[1mComponent\tPackages\n"
+	// ComponentLinksHeader is a table heading for dependencies between components
+	ComponentLinksHeader = "Component\tDepends On\tPackages\tDependencies\n"
+	// ComponentLinksHeaderColor is a table heading for dependencies between components, in color
+	ComponentLinksHeaderColor = "\033[1mComponent\tDepends On\tPackages\tDependencies\n"
+)
+
+// ComponentsRun carries out the "components" subcommand
+func ComponentsRun(r *cmd.Root, c *cmd.Sub) {
+	flags := r.Flags.(*GlobalFlags)
+	subFlags := c.Flags.(*ComponentsFlags)
+	ctx, stop := interruptible()
+	defer stop()
+	s := storage.NewStore()
+	curr, err := user.Current()
+	if err != nil {
+		fmt.Printf(UserErrorFormat, err.Error())
+		os.Exit(1)
+	}
+	if err = s.OpenReadOnlyContext(ctx, curr.HomeDir+DefaultDBLocation); err != nil {
+		fmt.Printf(DBOpenErrorFormat, err.Error())
+		os.Exit(1)
+	}
+	defer s.Close()
+	checkIndex(ctx, r, s, curr.HomeDir+DefaultDBLocation)
+	pkgs, deps, err := s.GetGraphContext(ctx)
+	if err != nil {
+		fmt.Printf("Failed to get dependencies, reason: '%s'\n", err.Error())
+		os.Exit(exitCode(err))
+	}
+	stop()
+	sizes, links := graph.New(pkgs, deps).Components(subFlags.Levels)
+	match := componentMatcher(subFlags.Component)
+	var names []string
+	for name := range sizes {
+		if match(name) {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		fmt.Printf("No components found.\n\n")
+		return
+	}
+	sort.Strings(names)
+	var reset string
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	if flags.NoColor {
+		fmt.Fprintf(w, ComponentsHeader)
+	} else {
+		fmt.Fprintf(w, ComponentsHeaderColor)
+		reset = "\033[0m"
+	}
+	for _, name := range names {
+		fmt.Fprintf(w, "%s%s\t%d\n", reset, name, sizes[name])
+	}
+	w.Flush()
+	fmt.Println()
+	if flags.NoColor {
+		fmt.Fprintf(w, ComponentLinksHeader)
+	} else {
+		fmt.Fprintf(w, ComponentLinksHeaderColor)
+	}
+	for _, link := range links {
+		if match(link.From) {
+			fmt.Fprintf(w, "%s%s\t%s\t%d\t%d\n", reset, link.From, link.To, link.Packages, link.Dependencies)
+		}
+	}
+	w.Flush()
+	fmt.Println()
+}
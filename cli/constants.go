@@ -34,4 +34,14 @@ const (
 	PackageFormatColor = "\033[1mPackage:\033[0m %s\n\n"
 	RowFormat          = "%s\t%d\n"
 	RowFormatColor     = "\033[0m%s\t%d\n"
+	ReleaseFormat      = "%s\t%d\t%d\n"
+	ReleaseFormatColor = "\033[0m%s\t%d\t%d\n"
+	DepthFormat        = "Depth %-4d: %d\n"
+	DepthFormatColor   = "\033[0mDepth %-4d: %d\n"
+)
+
+// Table Headers
+const (
+	DepthHeader      = "Packages by Depth"
+	DepthHeaderColor = "\033[1mPackages by Depth"
 )
@@ -17,67 +17,66 @@
 package cli
 
 import (
-	"database/sql"
 	"fmt"
 	"github.com/DataDrake/cli-ng/v2/cmd"
 	"github.com/DataDrake/eopkg-deps/storage"
 	"os"
 	"os/user"
-	"strings"
 )
 
 func init() {
 	cmd.Register(&Done)
 }
 
-// Done marks a package as rebuilt and optionally marks its reverse dependencies for rebuilds
+// Done marks packages as rebuilt and optionally marks their reverse dependencies for rebuilds
 var Done = cmd.Sub{
 	Name:  "done",
 	Alias: "do",
-	Short: "Mark a package as rebuilt, marking reverse deps for rebuilds",
+	Short: "Mark packages as rebuilt, marking reverse deps for rebuilds, reading stdin if none are listed or with '-f -'",
+	Flags: &DoneFlags{},
 	Args:  &DoneArgs{},
 	Run:   DoneRun,
 }
 
+// DoneFlags contains the additional flags for the "done" subcommand
+type DoneFlags struct {
+	NoContinue bool   `short:"n" long:"no-continue" desc:"do not queue the reverse deps"`
+	FromFile   string `short:"f" long:"from-file" desc:"read package names from a file, one per line, or '-' for stdin"`
+}
+
 // DoneArgs contains the arguments for the "done" subcommand
 type DoneArgs struct {
-	Name     string `desc:"the name of the package that was rebuilt"`
-	Continue string `desc:"queue the reverse deps? (yes/no)"`
+	Names []string `zero:"yes" desc:"the names of the packages that were rebuilt"`
 }
 
 // DoneRun carries out the "done" subcommand
 func DoneRun(r *cmd.Root, c *cmd.Sub) {
 	//flags := r.Flags.(*GlobalFlags)
+	flags := c.Flags.(*DoneFlags)
 	args := c.Args.(*DoneArgs)
-	var Continue bool
-	args.Continue = strings.ToLower(args.Continue)
-	switch args.Continue {
-	case "yes", "y", "true", "t":
-		Continue = true
-	case "no", "n", "false", "f":
-		Continue = false
-	default:
-		fmt.Println("Coninue must be some flavor of (Y)es or (N)o")
+	names, err := readNames(args.Names, flags.FromFile)
+	if err != nil {
+		fmt.Printf("Failed to read package names, reason: '%s'\n", err.Error())
 		os.Exit(1)
 	}
+	ctx, stop := interruptible()
+	defer stop()
 	s := storage.NewStore()
 	curr, err := user.Current()
 	if err != nil {
 		fmt.Printf(UserErrorFormat, err.Error())
 		os.Exit(1)
 	}
-	if err = s.Open(curr.HomeDir + DefaultDBLocation); err != nil {
+	if err = s.OpenContext(ctx, curr.HomeDir+DefaultDBLocation); err != nil {
 		fmt.Printf(DBOpenErrorFormat, err.Error())
 		os.Exit(1)
 	}
 	defer s.Close()
-	if err = s.DoneToDo(args.Name, Continue); err == sql.ErrNoRows {
-		fmt.Printf("Package '%s' does not exist or you need to update\n", args.Name)
-		os.Exit(1)
-	}
-	if err != nil {
+	if err = s.DoneToDoContext(ctx, !flags.NoContinue, names...); err != nil {
 		fmt.Printf("Failed to mark as rebuilt , reason: '%s'\n", err.Error())
-		os.Exit(1)
+		os.Exit(exitCode(err))
+	}
+	for _, name := range names {
+		fmt.Printf("Successfully marked '%s' as rebuilt\n", name)
 	}
-	fmt.Printf("Successfully marked '%s' as rebuilt\n", args.Name)
 }
@@ -0,0 +1,83 @@
+//
+// Copyright 2018-2021 Bryan T. Meyers <root@datadrake.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package cli
+
+import (
+	"bufio"
+	"fmt"
+	"github.com/DataDrake/cli-ng/v2/cmd"
+	"github.com/DataDrake/eopkg-deps/storage"
+	"os"
+	"os/user"
+)
+
+func init() {
+	cmd.Register(&Dump)
+}
+
+// Dump writes out the entire datastore as JSON
+var Dump = cmd.Sub{
+	Name:  "dump",
+	Alias: "dp",
+	Short: "Export packages, dependencies, the todo list and build history as JSON",
+	Flags: &DumpFlags{},
+	Run:   DumpRun,
+}
+
+// DumpFlags contains the additional flags for the "dump" subcommand
+type DumpFlags struct {
+	Output string `short:"o" long:"output" desc:"write to a file instead of stdout"`
+}
+
+// DumpRun carries out the "dump" subcommand
+func DumpRun(r *cmd.Root, c *cmd.Sub) {
+	subFlags := c.Flags.(*DumpFlags)
+	ctx, stop := interruptible()
+	defer stop()
+	s := storage.NewStore()
+	curr, err := user.Current()
+	if err != nil {
+		fmt.Printf(UserErrorFormat, err.Error())
+		os.Exit(1)
+	}
+	if err = s.OpenReadOnlyContext(ctx, curr.HomeDir+DefaultDBLocation); err != nil {
+		fmt.Printf(DBOpenErrorFormat, err.Error())
+		os.Exit(1)
+	}
+	defer s.Close()
+	d, err := s.DumpContext(ctx)
+	if err != nil {
+		fmt.Printf("Failed to dump DB, reason: '%s'\n", err.Error())
+		os.Exit(exitCode(err))
+	}
+	out := os.Stdout
+	if len(subFlags.Output) > 0 {
+		if out, err = os.Create(subFlags.Output); err != nil {
+			fmt.Printf("Failed to create output, reason: '%s'\n", err.Error())
+			os.Exit(1)
+		}
+		defer out.Close()
+	}
+	w := bufio.NewWriter(out)
+	if err = d.Write(w); err == nil {
+		err = w.Flush()
+	}
+	if err != nil {
+		fmt.Printf("Failed to write dump, reason: '%s'\n", err.Error())
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,153 @@
+//
+// Copyright 2018-2021 Bryan T. Meyers <root@datadrake.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package cli
+
+import (
+	"fmt"
+	"github.com/DataDrake/cli-ng/v2/cmd"
+	"github.com/DataDrake/eopkg-deps/graph"
+	"github.com/DataDrake/eopkg-deps/storage"
+	"os"
+	"os/user"
+	"text/tabwriter"
+	"time"
+)
+
+func init() {
+	cmd.Register(&Estimate)
+}
+
+// Estimate predicts how long the remaining rebuilds will take
+var Estimate = cmd.Sub{
+	Name:  "estimate",
+	Alias: "eta",
+	Short: "Estimate the critical path and finish time of the todo list",
+	Flags: &EstimateFlags{
+		Builders: 1,
+	},
+	Run: EstimateRun,
+}
+
+// EstimateFlags contains the additional flags for the "estimate" subcommand
+type EstimateFlags struct {
+	Builders int `short:"j" long:"builders" desc:"number of packages built in parallel"`
+	Default  int `short:"d" long:"default" desc:"minutes to assume for packages without a recorded duration"`
+}
+
+const (
+	// CriticalPathHeader is a table heading for the critical path
+	CriticalPathHeader = "Critical Path\tDuration\n"
+	// CriticalPathHeaderColor is a table heading for the critical path, in color
+	CriticalPathHeaderColor = "\033[1mCritical Path\tDuration\n"
+)
+
+// seconds converts a count of seconds to a printable Duration
+func seconds(count int) time.Duration {
+	return time.Duration(count) * time.Second
+}
+
+// EstimateRun carries out the "estimate" subcommand
+func EstimateRun(r *cmd.Root, c *cmd.Sub) {
+	flags := r.Flags.(*GlobalFlags)
+	subFlags := c.Flags.(*EstimateFlags)
+	if subFlags.Builders < 1 {
+		fmt.Println("The number of builders must be at least one")
+		os.Exit(1)
+	}
+	if subFlags.Default < 0 {
+		fmt.Println("The default duration cannot be negative")
+		os.Exit(1)
+	}
+	ctx, stop := interruptible()
+	defer stop()
+	s := storage.NewStore()
+	curr, err := user.Current()
+	if err != nil {
+		fmt.Printf(UserErrorFormat, err.Error())
+		os.Exit(1)
+	}
+	if err = s.OpenReadOnlyContext(ctx, curr.HomeDir+DefaultDBLocation); err != nil {
+		fmt.Printf(DBOpenErrorFormat, err.Error())
+		os.Exit(1)
+	}
+	defer s.Close()
+	checkIndex(ctx, r, s, curr.HomeDir+DefaultDBLocation)
+	pending, deps, err := s.GetPendingContext(ctx)
+	if err != nil {
+		fmt.Printf("Failed to get todo list, reason: '%s'\n", err.Error())
+		os.Exit(exitCode(err))
+	}
+	if len(pending) == 0 {
+		fmt.Printf("No todo items found.\n\n")
+		return
+	}
+	recorded, err := s.GetDurationsContext(ctx)
+	if err != nil {
+		fmt.Printf("Failed to get durations, reason: '%s'\n", err.Error())
+		os.Exit(exitCode(err))
+	}
+	stop()
+	// Fall back to the average of every recorded build
+	fallback := subFlags.Default * 60
+	durations := make(map[string]int)
+	total := 0
+	for _, pkg := range recorded {
+		durations[pkg.Name] = pkg.Duration
+		total += pkg.Duration
+	}
+	if fallback == 0 && len(recorded) > 0 {
+		fallback = total / len(recorded)
+	}
+	unknown := 0
+	for i, pkg := range pending {
+		duration, ok := durations[pkg.Name]
+		if !ok {
+			duration = fallback
+			unknown++
+		}
+		pending[i].Duration = duration
+	}
+	if unknown > 0 && subFlags.Default == 0 && len(recorded) == 0 {
+		fmt.Println("No durations have been recorded, please use '--default' to provide one.")
+		os.Exit(1)
+	}
+	g := graph.New(pending, deps)
+	path, critical := g.CriticalPath()
+	makespan := g.Schedule(subFlags.Builders)
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	if flags.NoColor {
+		fmt.Fprintf(w, CriticalPathHeader)
+	} else {
+		fmt.Fprintf(w, CriticalPathHeaderColor)
+	}
+	for _, node := range path {
+		fmt.Fprintf(w, "%s\t%s\n", node.Name, seconds(node.Duration))
+	}
+	w.Flush()
+	fmt.Println()
+	format := "%-10s: %s\n"
+	if !flags.NoColor {
+		format = "\033[0m" + format
+	}
+	fmt.Printf(format, "Queued", fmt.Sprint(len(pending)))
+	fmt.Printf(format, "Unknown", fmt.Sprintf("%d (assumed %s each)", unknown, seconds(fallback)))
+	fmt.Printf(format, "Critical", seconds(critical))
+	fmt.Printf(format, "Builders", fmt.Sprint(subFlags.Builders))
+	fmt.Printf(format, "Remaining", seconds(makespan))
+	fmt.Printf(format, "Finish", time.Now().Add(seconds(makespan)).Format("2006-01-02 15:04"))
+	fmt.Println()
+}
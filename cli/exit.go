@@ -0,0 +1,52 @@
+//
+// Copyright 2018-2021 Bryan T. Meyers <root@datadrake.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package cli
+
+import (
+	"context"
+	"errors"
+	"github.com/DataDrake/eopkg-deps/index"
+	"github.com/DataDrake/eopkg-deps/storage"
+)
+
+// Exit Codes
+const (
+	ExitFailure        = 1
+	ExitNotFound       = 2
+	ExitAlreadyStarted = 3
+	ExitNotInToDo      = 4
+	ExitAlreadyDone    = 5
+	ExitInterrupted    = 130
+)
+
+// exitCode picks the exit code for an error returned by a Store or an Index
+func exitCode(err error) int {
+	switch {
+	case errors.Is(err, storage.ErrPackageNotFound), errors.Is(err, storage.ErrNotProvided),
+		errors.Is(err, index.ErrPackageNotFound):
+		return ExitNotFound
+	case errors.Is(err, storage.ErrAlreadyStarted):
+		return ExitAlreadyStarted
+	case errors.Is(err, storage.ErrNotInToDo):
+		return ExitNotInToDo
+	case errors.Is(err, storage.ErrAlreadyDone):
+		return ExitAlreadyDone
+	case errors.Is(err, context.Canceled):
+		return ExitInterrupted
+	}
+	return ExitFailure
+}
@@ -0,0 +1,253 @@
+//
+// Copyright 2018-2021 Bryan T. Meyers <root@datadrake.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package cli
+
+import (
+	"bufio"
+	"fmt"
+	"github.com/DataDrake/cli-ng/v2/cmd"
+	"github.com/DataDrake/eopkg-deps/graph"
+	"github.com/DataDrake/eopkg-deps/storage"
+	"io"
+	"os"
+	"os/user"
+	"strings"
+	"text/template"
+)
+
+func init() {
+	cmd.Register(&ExportPlan)
+}
+
+// ExportPlan writes out the worst-case rebuilds for packages as input for a build tool
+var ExportPlan = cmd.Sub{
+	Name:  "export-plan",
+	Alias: "ep",
+	Short: "Export the worst-case rebuilds as a Makefile, ninja file or shell script",
+	Flags: &ExportPlanFlags{
+		Format:  PlanMake,
+		Command: DefaultPlanCommand,
+	},
+	Args: &ExportPlanArgs{},
+	Run:  ExportPlanRun,
+}
+
+// ExportPlanFlags contains the additional flags for the "export-plan" subcommand
+type ExportPlanFlags struct {
+	Format  string `short:"f" long:"format" desc:"one of 'make', 'ninja' or 'sh'"`
+	Command string `short:"c" long:"command" desc:"template of the command to build a package, given {{.Name}} quoted for sh and {{.Release}}"`
+	Output  string `short:"o" long:"output" desc:"write to a file instead of stdout"`
+}
+
+// ExportPlanArgs contains the arguments for the "export-plan" subcommand
+type ExportPlanArgs struct {
+	Names []string `desc:"the names of the packages to rebuild"`
+}
+
+// Plan formats
+const (
+	PlanMake  = "make"
+	PlanNinja = "ninja"
+	PlanShell = "sh"
+)
+
+// DefaultPlanCommand builds a package from its directory in a package repository
+const DefaultPlanCommand = "make -C {{.Name}}"
+
+// plan is an ordered set of rebuilds with the dependencies between them
+type plan struct {
+	order   []*graph.Node
+	deps    map[*graph.Node][]*graph.Node
+	command *template.Template
+}
+
+// newPlan orders the packages in g, dropping any dependency which would form a cycle
+func newPlan(g *graph.Graph, command *template.Template) *plan {
+	p := &plan{
+		order:   g.Order(),
+		deps:    make(map[*graph.Node][]*graph.Node),
+		command: command,
+	}
+	built := make(map[*graph.Node]bool)
+	for _, node := range p.order {
+		for _, dep := range node.Deps {
+			if built[dep] {
+				p.deps[node] = append(p.deps[node], dep)
+			}
+		}
+		built[node] = true
+	}
+	return p
+}
+
+// shellQuote single-quotes a word for sh, so that nothing in it is expanded
+func shellQuote(word string) string {
+	return "'" + strings.ReplaceAll(word, "'", `'\''`) + "'"
+}
+
+// render fills in the command template for a package, with its name quoted for the shell
+func (p *plan) render(node *graph.Node) (string, error) {
+	quoted := *node
+	quoted.Name = shellQuote(node.Name)
+	var b strings.Builder
+	err := p.command.Execute(&b, &quoted)
+	return b.String(), err
+}
+
+// joinNames lists the names of the nodes, with each one escaped
+func joinNames(nodes []*graph.Node, escape func(string) string) string {
+	var list []string
+	for _, node := range nodes {
+		list = append(list, escape(node.Name))
+	}
+	return strings.Join(list, " ")
+}
+
+// writeMake writes the plan as a Makefile with a phony target per package
+func (p *plan) writeMake(w io.Writer) error {
+	escape := strings.NewReplacer("$", "$$", " ", `\ `, ":", `\:`, "#", `\#`).Replace
+	// A '%' only makes a pattern rule in a target, and is literal in prerequisites
+	target := func(name string) string { return strings.ReplaceAll(escape(name), "%", `\%`) }
+	value := strings.NewReplacer("$", "$$").Replace
+	all := joinNames(p.order, escape)
+	fmt.Fprintf(w, ".PHONY: all %s\n\nall: %s\n", all, all)
+	for _, node := range p.order {
+		command, err := p.render(node)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "\n%s:", target(node.Name))
+		if deps := p.deps[node]; len(deps) > 0 {
+			fmt.Fprintf(w, " %s", joinNames(deps, escape))
+		}
+		fmt.Fprintf(w, "\n\t%s\n", value(command))
+	}
+	return nil
+}
+
+// writeNinja writes the plan as a ninja file with a build edge per package
+func (p *plan) writeNinja(w io.Writer) error {
+	escape := strings.NewReplacer("$", "$$", " ", "$ ", ":", "$:").Replace
+	value := strings.NewReplacer("$", "$$").Replace
+	fmt.Fprintf(w, "rule rebuild\n  command = $cmd\n  description = Rebuilding $pkg\n")
+	for _, node := range p.order {
+		command, err := p.render(node)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "\nbuild %s: rebuild", escape(node.Name))
+		if deps := p.deps[node]; len(deps) > 0 {
+			fmt.Fprintf(w, " | %s", joinNames(deps, escape))
+		}
+		fmt.Fprintf(w, "\n  cmd = %s\n  pkg = %s\n", value(command), value(node.Name))
+	}
+	fmt.Fprintf(w, "\nbuild all: phony %s\n\ndefault all\n", joinNames(p.order, escape))
+	return nil
+}
+
+// writeShell writes the plan as a shell script which rebuilds each package in order
+func (p *plan) writeShell(w io.Writer) error {
+	fmt.Fprintf(w, "#!/bin/sh\nset -e\n")
+	for _, node := range p.order {
+		command, err := p.render(node)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "\n# %s\n%s\n", node.Name, command)
+	}
+	return nil
+}
+
+// ExportPlanRun carries out the "export-plan" subcommand
+func ExportPlanRun(r *cmd.Root, c *cmd.Sub) {
+	//flags := r.Flags.(*GlobalFlags)
+	subFlags := c.Flags.(*ExportPlanFlags)
+	args := c.Args.(*ExportPlanArgs)
+	var write func(*plan, io.Writer) error
+	switch subFlags.Format {
+	case PlanMake:
+		write = (*plan).writeMake
+	case PlanNinja:
+		write = (*plan).writeNinja
+	case PlanShell:
+		write = (*plan).writeShell
+	default:
+		fmt.Printf("Format must be one of '%s', '%s' or '%s'\n", PlanMake, PlanNinja, PlanShell)
+		os.Exit(1)
+	}
+	command, err := template.New("command").Parse(subFlags.Command)
+	if err != nil {
+		fmt.Printf("Failed to parse command, reason: '%s'\n", err.Error())
+		os.Exit(1)
+	}
+	ctx, stop := interruptible()
+	defer stop()
+	s := storage.NewStore()
+	curr, err := user.Current()
+	if err != nil {
+		fmt.Printf(UserErrorFormat, err.Error())
+		os.Exit(1)
+	}
+	if err = s.OpenReadOnlyContext(ctx, curr.HomeDir+DefaultDBLocation); err != nil {
+		fmt.Printf(DBOpenErrorFormat, err.Error())
+		os.Exit(1)
+	}
+	defer s.Close()
+	checkIndex(ctx, r, s, curr.HomeDir+DefaultDBLocation)
+	pkgs, deps, err := s.GetGraphContext(ctx)
+	if err != nil {
+		fmt.Printf("Failed to get dependencies, reason: '%s'\n", err.Error())
+		os.Exit(exitCode(err))
+	}
+	stop()
+	full := graph.New(pkgs, deps)
+	// Keep only the requested packages and everything which must be rebuilt after them
+	keep := make(map[string]bool)
+	for _, name := range args.Names {
+		node := full.Nodes[name]
+		if node == nil {
+			exitMissing(s, name)
+		}
+		keep[name] = true
+		for _, rev := range node.Closure(true) {
+			keep[rev.Name] = true
+		}
+	}
+	kept := make(storage.Packages, 0, len(keep))
+	for _, pkg := range pkgs {
+		if keep[pkg.Name] {
+			kept = append(kept, pkg)
+		}
+	}
+	p := newPlan(graph.New(kept, deps), command)
+	out := os.Stdout
+	if len(subFlags.Output) > 0 {
+		if out, err = os.Create(subFlags.Output); err != nil {
+			fmt.Printf("Failed to create output, reason: '%s'\n", err.Error())
+			os.Exit(1)
+		}
+		defer out.Close()
+	}
+	w := bufio.NewWriter(out)
+	if err = write(p, w); err == nil {
+		err = w.Flush()
+	}
+	if err != nil {
+		fmt.Printf("Failed to write plan, reason: '%s'\n", err.Error())
+		os.Exit(1)
+	}
+}
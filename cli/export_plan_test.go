@@ -0,0 +1,139 @@
+//
+// Copyright 2018-2021 Bryan T. Meyers <root@datadrake.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package cli
+
+import (
+	"github.com/DataDrake/eopkg-deps/graph"
+	"github.com/DataDrake/eopkg-deps/storage"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"text/template"
+)
+
+// testPlan makes a plan for a chain of packages, each depending on the one before it
+func testPlan(t *testing.T, command string, names ...string) *plan {
+	var pkgs storage.Packages
+	var deps storage.Dependencies
+	for i, name := range names {
+		pkgs = append(pkgs, storage.Package{Name: name, Release: i + 1})
+		if i > 0 {
+			deps = append(deps, storage.Dependency{Left: name, Right: names[i-1]})
+		}
+	}
+	tmpl, err := template.New("command").Parse(command)
+	if err != nil {
+		t.Fatalf("Failed to parse command template, reason: '%s'", err)
+	}
+	return newPlan(graph.New(pkgs, deps), tmpl)
+}
+
+func TestNewPlan(t *testing.T) {
+	// a and b need each other, and c needs a
+	pkgs := storage.Packages{{Name: "a"}, {Name: "b"}, {Name: "c"}}
+	deps := storage.Dependencies{{Left: "a", Right: "b"}, {Left: "b", Right: "a"}, {Left: "c", Right: "a"}}
+	p := newPlan(graph.New(pkgs, deps), nil)
+	found := make(map[string][]string)
+	for _, node := range p.order {
+		found[node.Name] = []string{}
+		for _, dep := range p.deps[node] {
+			found[node.Name] = append(found[node.Name], dep.Name)
+		}
+	}
+	// The cycle is broken at 'a', so its dependency on 'b' is dropped
+	expected := map[string][]string{"a": {}, "b": {"a"}, "c": {"a"}}
+	if !reflect.DeepEqual(found, expected) {
+		t.Errorf("newPlan: dependencies are %v, expected %v", found, expected)
+	}
+}
+
+func TestWritePlan(t *testing.T) {
+	tests := []struct {
+		what     string
+		write    func(*plan, io.Writer) error
+		names    []string
+		expected string
+	}{
+		{"make", (*plan).writeMake, []string{"zlib", "a$b", "c d:e#f%"}, `.PHONY: all zlib a$$b c\ d\:e\#f%
+
+all: zlib a$$b c\ d\:e\#f%
+
+zlib:
+	make -C 'zlib' R=1
+
+a$$b: zlib
+	make -C 'a$$b' R=2
+
+c\ d\:e\#f\%: a$$b
+	make -C 'c d:e#f%' R=3
+`},
+		{"ninja", (*plan).writeNinja, []string{"zlib", "c d", "e:f$"}, `rule rebuild
+  command = $cmd
+  description = Rebuilding $pkg
+
+build zlib: rebuild
+  cmd = make -C 'zlib' R=1
+  pkg = zlib
+
+build c$ d: rebuild | zlib
+  cmd = make -C 'c d' R=2
+  pkg = c d
+
+build e$:f$$: rebuild | c$ d
+  cmd = make -C 'e:f$$' R=3
+  pkg = e:f$$
+
+build all: phony zlib c$ d e$:f$$
+
+default all
+`},
+		{"sh", (*plan).writeShell, []string{"zlib", "a$b", "it's"}, `#!/bin/sh
+set -e
+
+# zlib
+make -C 'zlib' R=1
+
+# a$b
+make -C 'a$b' R=2
+
+# it's
+make -C 'it'\''s' R=3
+`},
+		{"empty make", (*plan).writeMake, nil, ".PHONY: all \n\nall: \n"},
+		{"empty ninja", (*plan).writeNinja, nil, "rule rebuild\n  command = $cmd\n  description = Rebuilding $pkg\n\nbuild all: phony \n\ndefault all\n"},
+		{"empty sh", (*plan).writeShell, nil, "#!/bin/sh\nset -e\n"},
+	}
+	for _, test := range tests {
+		var b strings.Builder
+		if err := test.write(testPlan(t, "make -C {{.Name}} R={{.Release}}", test.names...), &b); err != nil {
+			t.Errorf("%s: unexpected error: %s", test.what, err)
+			continue
+		}
+		if b.String() != test.expected {
+			t.Errorf("%s: found\n%s\nexpected\n%s", test.what, b.String(), test.expected)
+		}
+	}
+}
+
+func TestWritePlanBadCommand(t *testing.T) {
+	for _, write := range []func(*plan, io.Writer) error{(*plan).writeMake, (*plan).writeNinja, (*plan).writeShell} {
+		if err := write(testPlan(t, "{{.Missing}}", "zlib"), io.Discard); err == nil {
+			t.Error("Expected an error rendering a template with a missing field")
+		}
+	}
+}
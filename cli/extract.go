@@ -0,0 +1,60 @@
+//
+// Copyright 2018-2021 Bryan T. Meyers <root@datadrake.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package cli
+
+import (
+	"bufio"
+	"fmt"
+	"github.com/DataDrake/eopkg-deps/index"
+	"os"
+)
+
+// extractIndex writes out part of the eopkg index as a smaller, self-contained index, for the "index extract" action
+func extractIndex(subFlags *IndexFlags, names []string) {
+	i := index.NewIndex()
+	err := i.Load(DefaultIndexLocation)
+	if err != nil {
+		fmt.Printf("Failed to load index, reason: '%s'\n", err.Error())
+		os.Exit(1)
+	}
+	var sub *index.Index
+	if subFlags.Reverse {
+		sub, err = i.Reverse(names...)
+	} else {
+		sub, err = i.Forward(names...)
+	}
+	if err != nil {
+		fmt.Printf("Failed to extract packages, reason: '%s'\n", err.Error())
+		os.Exit(exitCode(err))
+	}
+	out := os.Stdout
+	if len(subFlags.Output) > 0 {
+		if out, err = os.Create(subFlags.Output); err != nil {
+			fmt.Printf("Failed to create output, reason: '%s'\n", err.Error())
+			os.Exit(1)
+		}
+		defer out.Close()
+	}
+	w := bufio.NewWriter(out)
+	if err = sub.Write(w); err == nil {
+		err = w.Flush()
+	}
+	if err != nil {
+		fmt.Printf("Failed to write index, reason: '%s'\n", err.Error())
+		os.Exit(1)
+	}
+}
@@ -17,7 +17,7 @@
 package cli
 
 import (
-	"database/sql"
+	"errors"
 	"fmt"
 	"github.com/DataDrake/cli-ng/v2/cmd"
 	"github.com/DataDrake/eopkg-deps/storage"
@@ -36,10 +36,16 @@ var Forward = cmd.Sub{
 	Name:  "forward",
 	Alias: "fwd",
 	Short: "Get this package's dependencies",
+	Flags: &ForwardFlags{},
 	Args:  &ForwardArgs{},
 	Run:   ForwardRun,
 }
 
+// ForwardFlags contains the additional flags for the "forward" subcommand
+type ForwardFlags struct {
+	Component string `short:"c" long:"component" desc:"only show packages in these comma-separated components"`
+}
+
 // ForwardArgs contains the arguments for the "forward" subcommand
 type ForwardArgs struct {
 	Package string `desc:"the name of the package"`
@@ -47,35 +53,39 @@ type ForwardArgs struct {
 
 const (
 	// DependencyHeader is a table heading for forward dependencies
-	DependencyHeader = "Dependency\tSince Release\n"
+	DependencyHeader = "Dependency\tSince Release\tCurrent\n"
 	// DependencyHeaderColor is a table heading for forward dependencies, in color
-	DependencyHeaderColor = "\033[1mDependency\tSince Release\n"
+	DependencyHeaderColor = "\033[1mDependency\tSince Release\tCurrent\n"
 )
 
 // ForwardRun carries out the "forward" subcommand
 func ForwardRun(r *cmd.Root, c *cmd.Sub) {
 	flags := r.Flags.(*GlobalFlags)
+	subFlags := c.Flags.(*ForwardFlags)
 	args := c.Args.(*ForwardArgs)
+	ctx, stop := interruptible()
+	defer stop()
 	s := storage.NewStore()
 	curr, err := user.Current()
 	if err != nil {
 		fmt.Printf(UserErrorFormat, err.Error())
 		os.Exit(1)
 	}
-	if err = s.Open(curr.HomeDir + DefaultDBLocation); err != nil {
+	if err = s.OpenReadOnlyContext(ctx, curr.HomeDir+DefaultDBLocation); err != nil {
 		fmt.Printf(DBOpenErrorFormat, err.Error())
 		os.Exit(1)
 	}
 	defer s.Close()
-	rights, err := s.GetForward(args.Package)
-	if err == sql.ErrNoRows {
-		fmt.Printf("Package '%s' does not exist or you need to update\n", args.Package)
-		os.Exit(1)
+	checkIndex(ctx, r, s, curr.HomeDir+DefaultDBLocation)
+	rights, err := s.GetForwardContext(ctx, args.Package)
+	if errors.Is(err, storage.ErrPackageNotFound) {
+		exitMissing(s, args.Package)
 	}
 	if err != nil {
 		fmt.Printf("Failed to get forward deps, reason: '%s'\n", err.Error())
-		os.Exit(1)
+		os.Exit(exitCode(err))
 	}
+	rights = filterComponents(rights, subFlags.Component)
 	sort.Sort(rights)
 	if flags.NoColor {
 		fmt.Printf(PackageFormat, args.Package)
@@ -90,13 +100,13 @@ func ForwardRun(r *cmd.Root, c *cmd.Sub) {
 	var rowFormat string
 	if flags.NoColor {
 		fmt.Fprintf(w, DependencyHeader)
-		rowFormat = RowFormat
+		rowFormat = ReleaseFormat
 	} else {
 		fmt.Fprintf(w, DependencyHeaderColor)
-		rowFormat = RowFormatColor
+		rowFormat = ReleaseFormatColor
 	}
 	for _, right := range rights {
-		fmt.Fprintf(w, rowFormat, right.Name, right.Release)
+		fmt.Fprintf(w, rowFormat, right.Name, right.Release, right.Current)
 	}
 	w.Flush()
 	fmt.Printf("\nTotal: %d\n", len(rights))
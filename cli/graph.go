@@ -0,0 +1,82 @@
+//
+// Copyright 2018-2021 Bryan T. Meyers <root@datadrake.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package cli
+
+import (
+	"bufio"
+	"fmt"
+	"github.com/DataDrake/cli-ng/v2/cmd"
+	"github.com/DataDrake/eopkg-deps/graph"
+	"github.com/DataDrake/eopkg-deps/storage"
+	"os"
+	"os/user"
+)
+
+func init() {
+	cmd.Register(&Graph)
+}
+
+// Graph prints the dependencies between packages in the DOT format
+var Graph = cmd.Sub{
+	Name:  "graph",
+	Alias: "gr",
+	Short: "Print the dependency graph in the DOT format",
+	Flags: &GraphFlags{},
+	Run:   GraphRun,
+}
+
+// GraphFlags contains the additional flags for the "graph" subcommand
+type GraphFlags struct {
+	Component string `short:"c" long:"component" desc:"only show packages in these comma-separated components"`
+}
+
+// GraphRun carries out the "graph" subcommand
+func GraphRun(r *cmd.Root, c *cmd.Sub) {
+	//flags := r.Flags.(*GlobalFlags)
+	subFlags := c.Flags.(*GraphFlags)
+	ctx, stop := interruptible()
+	defer stop()
+	s := storage.NewStore()
+	curr, err := user.Current()
+	if err != nil {
+		fmt.Printf(UserErrorFormat, err.Error())
+		os.Exit(1)
+	}
+	if err = s.OpenReadOnlyContext(ctx, curr.HomeDir+DefaultDBLocation); err != nil {
+		fmt.Printf(DBOpenErrorFormat, err.Error())
+		os.Exit(1)
+	}
+	defer s.Close()
+	checkIndex(ctx, r, s, curr.HomeDir+DefaultDBLocation)
+	pkgs, deps, err := s.GetGraphContext(ctx)
+	if err != nil {
+		fmt.Printf("Failed to get dependencies, reason: '%s'\n", err.Error())
+		os.Exit(exitCode(err))
+	}
+	stop()
+	g := graph.New(filterComponents(pkgs, subFlags.Component), deps)
+	w := bufio.NewWriter(os.Stdout)
+	fmt.Fprintf(w, "digraph {\nranksep=2;\nrankdir=LR;\n")
+	for _, node := range g.Sorted() {
+		fmt.Fprintf(w, "    %q;\n", node.Name)
+		for _, dep := range node.Deps {
+			fmt.Fprintf(w, "    %q -> %q;\n", node.Name, dep.Name)
+		}
+	}
+	fmt.Fprintf(w, "}\n")
+	w.Flush()
+}
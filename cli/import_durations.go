@@ -0,0 +1,129 @@
+//
+// Copyright 2018-2021 Bryan T. Meyers <root@datadrake.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package cli
+
+import (
+	"encoding/csv"
+	"fmt"
+	"github.com/DataDrake/cli-ng/v2/cmd"
+	"github.com/DataDrake/eopkg-deps/storage"
+	"io"
+	"os"
+	"os/user"
+	"strconv"
+	"strings"
+	"time"
+)
+
+func init() {
+	cmd.Register(&ImportDurations)
+}
+
+// ImportDurations loads historical build durations from a CSV file
+var ImportDurations = cmd.Sub{
+	Name:  "import-durations",
+	Alias: "id",
+	Short: "Import build durations from a CSV of package,duration rows",
+	Args:  &ImportDurationsArgs{},
+	Run:   ImportDurationsRun,
+}
+
+// ImportDurationsArgs contains the arguments for the "import-durations" subcommand
+type ImportDurationsArgs struct {
+	File string `desc:"CSV file where durations are in seconds or like '1h20m'"`
+}
+
+// parseDuration reads a duration as either whole seconds or a Go duration string
+func parseDuration(raw string) (int, error) {
+	raw = strings.TrimSpace(raw)
+	seconds, err := strconv.Atoi(raw)
+	if err != nil {
+		d, err := time.ParseDuration(raw)
+		if err != nil {
+			return 0, fmt.Errorf("'%s' is not a valid duration", raw)
+		}
+		seconds = int(d.Seconds())
+	}
+	if seconds < 0 {
+		return 0, fmt.Errorf("'%s' is a negative duration", raw)
+	}
+	return seconds, nil
+}
+
+// readDurations parses package,duration rows, skipping a header row if present
+func readDurations(r io.Reader) (storage.Packages, error) {
+	pkgs := make(storage.Packages, 0)
+	reader := csv.NewReader(r)
+	reader.Comment = '#'
+	reader.FieldsPerRecord = 2
+	for line := 1; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		duration, err := parseDuration(record[1])
+		if err != nil {
+			if line == 1 && !strings.HasPrefix(strings.TrimSpace(record[1]), "-") {
+				continue
+			}
+			return nil, fmt.Errorf("line %d: %s", line, err)
+		}
+		pkgs = append(pkgs, storage.Package{
+			Name:     strings.TrimSpace(record[0]),
+			Duration: duration,
+		})
+	}
+	return pkgs, nil
+}
+
+// ImportDurationsRun carries out the "import-durations" subcommand
+func ImportDurationsRun(r *cmd.Root, c *cmd.Sub) {
+	//flags := r.Flags.(*GlobalFlags)
+	args := c.Args.(*ImportDurationsArgs)
+	f, err := os.Open(args.File)
+	if err != nil {
+		fmt.Printf("Failed to open durations, reason: '%s'\n", err.Error())
+		os.Exit(1)
+	}
+	pkgs, err := readDurations(f)
+	f.Close()
+	if err != nil {
+		fmt.Printf("Failed to read durations, reason: '%s'\n", err.Error())
+		os.Exit(1)
+	}
+	ctx, stop := interruptible()
+	defer stop()
+	s := storage.NewStore()
+	curr, err := user.Current()
+	if err != nil {
+		fmt.Printf(UserErrorFormat, err.Error())
+		os.Exit(1)
+	}
+	if err = s.OpenContext(ctx, curr.HomeDir+DefaultDBLocation); err != nil {
+		fmt.Printf(DBOpenErrorFormat, err.Error())
+		os.Exit(1)
+	}
+	defer s.Close()
+	if err = s.SetDurationsContext(ctx, pkgs); err != nil {
+		fmt.Printf("Failed to import durations, reason: '%s'\n", err.Error())
+		os.Exit(exitCode(err))
+	}
+	fmt.Printf("Successfully imported %d durations\n", len(pkgs))
+}
@@ -0,0 +1,61 @@
+//
+// Copyright 2018-2021 Bryan T. Meyers <root@datadrake.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package cli
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseDuration(t *testing.T) {
+	tests := []struct {
+		raw     string
+		seconds int
+		ok      bool
+	}{
+		{"90", 90, true},
+		{" 90 ", 90, true},
+		{"0", 0, true},
+		{"1h20m", 4800, true},
+		{"1m30.5s", 90, true},
+		{"-1", 0, false},
+		{"-5m", 0, false},
+		{"duration", 0, false},
+		{"", 0, false},
+	}
+	for _, test := range tests {
+		seconds, err := parseDuration(test.raw)
+		if (err == nil) != test.ok || seconds != test.seconds {
+			t.Errorf("parseDuration(%q) = %d, %v, expected %d, ok %t", test.raw, seconds, err, test.seconds, test.ok)
+		}
+	}
+}
+
+func TestReadDurations(t *testing.T) {
+	pkgs, err := readDurations(strings.NewReader("package,duration\n# comment\ncurl,60\ngit, 1m\n"))
+	if err != nil {
+		t.Fatalf("readDurations: unexpected error: %s", err)
+	}
+	if len(pkgs) != 2 || pkgs[0].Name != "curl" || pkgs[0].Duration != 60 || pkgs[1].Name != "git" || pkgs[1].Duration != 60 {
+		t.Errorf("readDurations: unexpected packages %+v", pkgs)
+	}
+	for _, csv := range []string{"curl,-60\n", "curl,60\ngit,-1m\n", "curl,60\ngit,soon\n"} {
+		if _, err := readDurations(strings.NewReader(csv)); err == nil {
+			t.Errorf("readDurations(%q): expected an error", csv)
+		}
+	}
+}
@@ -0,0 +1,79 @@
+//
+// Copyright 2018-2021 Bryan T. Meyers <root@datadrake.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package cli
+
+import (
+	"fmt"
+	"github.com/DataDrake/cli-ng/v2/cmd"
+	"os"
+)
+
+func init() {
+	cmd.Register(&Index)
+}
+
+// Index works with the eopkg index directly, rather than the datastore built from it
+var Index = cmd.Sub{
+	Name:  "index",
+	Alias: "ix",
+	Short: "Work with the eopkg index itself: 'extract' writes the packages needed by (or needing) some packages as a self-contained index, 'lint' checks for packages which cannot be imported as is",
+	Flags: &IndexFlags{},
+	Args:  &IndexArgs{},
+	Run:   IndexRun,
+}
+
+// IndexFlags contains the additional flags for the "index" subcommand
+type IndexFlags struct {
+	Reverse bool   `short:"r" long:"reverse" desc:"extract the packages which depend on these instead"`
+	Output  string `short:"o" long:"output" desc:"extract to a file instead of stdout"`
+	Quiet   bool   `short:"q" long:"quiet" desc:"only report errors from lint, not warnings"`
+}
+
+// IndexArgs contains the arguments for the "index" subcommand
+type IndexArgs struct {
+	Action   string   `desc:"'extract' or 'lint'"`
+	Packages []string `zero:"yes" desc:"packages to extract"`
+}
+
+// Index actions
+const (
+	IndexExtract = "extract"
+	IndexLint    = "lint"
+)
+
+// IndexRun carries out the "index" subcommand
+func IndexRun(r *cmd.Root, c *cmd.Sub) {
+	subFlags := c.Flags.(*IndexFlags)
+	args := c.Args.(*IndexArgs)
+	switch args.Action {
+	case IndexExtract:
+		if len(args.Packages) == 0 {
+			fmt.Println("At least one package to extract is needed")
+			os.Exit(1)
+		}
+		extractIndex(subFlags, args.Packages)
+	case IndexLint:
+		if len(args.Packages) > 0 {
+			fmt.Println("Lint checks the whole index, so it takes no packages")
+			os.Exit(1)
+		}
+		lintIndex(subFlags)
+	default:
+		fmt.Printf("Action must be '%s' or '%s'\n", IndexExtract, IndexLint)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,154 @@
+//
+// Copyright 2018-2021 Bryan T. Meyers <root@datadrake.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"github.com/DataDrake/cli-ng/v2/cmd"
+	"github.com/DataDrake/eopkg-deps/index"
+	"github.com/DataDrake/eopkg-deps/storage"
+	"os"
+	"os/user"
+	"path/filepath"
+	"sort"
+)
+
+func init() {
+	cmd.Register(&Ingest)
+}
+
+// Ingest marks packages as rebuilt from the .eopkg files produced by a build
+var Ingest = cmd.Sub{
+	Name:  "ingest",
+	Alias: "in",
+	Short: "Mark packages as rebuilt from built .eopkg files",
+	Args:  &IngestArgs{},
+	Run:   IngestRun,
+}
+
+// IngestArgs contains the arguments for the "ingest" subcommand
+type IngestArgs struct {
+	Paths []string `desc:"directories or .eopkg files to read"`
+}
+
+// EopkgExtension is the file extension of built packages
+const EopkgExtension = ".eopkg"
+
+// findArtifacts expands directories into the .eopkg files they contain
+func findArtifacts(paths []string) ([]string, error) {
+	var files []string
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			files = append(files, path)
+			continue
+		}
+		matches, err := filepath.Glob(filepath.Join(path, "*"+EopkgExtension))
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, matches...)
+	}
+	return files, nil
+}
+
+// IngestRun carries out the "ingest" subcommand
+func IngestRun(r *cmd.Root, c *cmd.Sub) {
+	//flags := r.Flags.(*GlobalFlags)
+	args := c.Args.(*IngestArgs)
+	files, err := findArtifacts(args.Paths)
+	if err != nil {
+		fmt.Printf("Failed to find packages, reason: '%s'\n", err.Error())
+		os.Exit(1)
+	}
+	if len(files) == 0 {
+		fmt.Println("No packages found.")
+		return
+	}
+	// Keep only the newest artifact for each package
+	latest := make(map[string]*index.Package)
+	for _, file := range files {
+		pkg, err := index.LoadMetadata(file)
+		if err != nil {
+			fmt.Printf("Failed to read package, reason: '%s'\n", err.Error())
+			os.Exit(1)
+		}
+		prev, ok := latest[pkg.Name]
+		if !ok || prev.Releases[0].Number < pkg.Releases[0].Number {
+			latest[pkg.Name] = pkg
+		}
+	}
+	var names []string
+	for name := range latest {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	ctx, stop := interruptible()
+	defer stop()
+	s := storage.NewStore()
+	curr, err := user.Current()
+	if err != nil {
+		fmt.Printf(UserErrorFormat, err.Error())
+		os.Exit(1)
+	}
+	if err = s.OpenContext(ctx, curr.HomeDir+DefaultDBLocation); err != nil {
+		fmt.Printf(DBOpenErrorFormat, err.Error())
+		os.Exit(1)
+	}
+	defer s.Close()
+	failed := 0
+	for _, name := range names {
+		if ctx.Err() != nil {
+			fmt.Println("Interrupted, skipping the remaining packages")
+			os.Exit(ExitInterrupted)
+		}
+		release := latest[name].Releases[0].Number
+		prev, err := s.GetPackageContext(ctx, name)
+		if errors.Is(err, storage.ErrPackageNotFound) {
+			fmt.Printf("Skipping '%s', not tracked\n", name)
+			continue
+		}
+		if err != nil {
+			fmt.Printf("Failed to get package '%s', reason: '%s'\n", name, err.Error())
+			failed++
+			continue
+		}
+		if release <= prev.Release {
+			fmt.Printf("Skipping '%s', release %d is not newer than %d\n", name, release, prev.Release)
+			continue
+		}
+		// Recording the release and marking it done happen together, or not at all
+		queued, err := s.RebuiltContext(ctx, name, release)
+		if err != nil {
+			fmt.Printf("Failed to mark '%s' as rebuilt, reason: '%s'\n", name, err.Error())
+			failed++
+			continue
+		}
+		if queued {
+			fmt.Printf("Successfully marked '%s' as rebuilt at release %d\n", name, release)
+		} else {
+			fmt.Printf("Recorded release %d for '%s', which was not pending in the todo list\n", release, name)
+		}
+	}
+	if failed > 0 {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,43 @@
+//
+// Copyright 2018-2021 Bryan T. Meyers <root@datadrake.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package cli
+
+import (
+	"fmt"
+	"github.com/DataDrake/eopkg-deps/index"
+	"os"
+)
+
+// lintIndex checks the eopkg index for problems before it gets imported, for the "index lint" action
+func lintIndex(subFlags *IndexFlags) {
+	findings, err := index.LintWalk(index.File(DefaultIndexLocation))
+	if err != nil {
+		fmt.Printf("Failed to load index, reason: '%s'\n", err.Error())
+		os.Exit(1)
+	}
+	for _, finding := range findings {
+		if subFlags.Quiet && finding.Severity < index.Error {
+			continue
+		}
+		fmt.Println(finding)
+	}
+	errs := findings.Count(index.Error)
+	fmt.Printf("Found %d errors and %d warnings\n", errs, findings.Count(index.Warning))
+	if errs > 0 {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,110 @@
+//
+// Copyright 2018-2021 Bryan T. Meyers <root@datadrake.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package cli
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"os"
+	"strings"
+)
+
+// ErrNoNames is returned when no package names were provided by any means
+var ErrNoNames = errors.New("no package names provided")
+
+// scanNames reads one package name per line, ignoring blank lines and '#' comments
+func scanNames(r io.Reader) ([]string, error) {
+	var names []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if len(line) == 0 || strings.HasPrefix(line, "#") {
+			continue
+		}
+		names = append(names, line)
+	}
+	return names, scanner.Err()
+}
+
+// StdinPath stands for stdin wherever a file of package names is expected
+const StdinPath = "-"
+
+// readNamesFile reads package names from a file, or from stdin for StdinPath
+func readNamesFile(path string) ([]string, error) {
+	if path == StdinPath {
+		return scanNames(os.Stdin)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return scanNames(f)
+}
+
+// stdinIsPipe checks if stdin is redirected rather than an interactive terminal
+func stdinIsPipe() bool {
+	info, err := os.Stdin.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice == 0
+}
+
+// readNames combines package names from arguments and a file, removing duplicates.
+// Names are read from stdin when neither is provided and stdin is not a terminal.
+func readNames(args []string, file string) ([]string, error) {
+	names := args
+	if len(file) > 0 {
+		more, err := readNamesFile(file)
+		if err != nil {
+			return nil, err
+		}
+		names = append(names, more...)
+	} else if len(names) == 0 && stdinIsPipe() {
+		more, err := scanNames(os.Stdin)
+		if err != nil {
+			return nil, err
+		}
+		names = more
+	}
+	if len(names) == 0 {
+		return nil, ErrNoNames
+	}
+	seen := make(map[string]bool)
+	var unique []string
+	for _, name := range names {
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+		unique = append(unique, name)
+	}
+	return unique, nil
+}
+
+// splitNames splits a comma-separated list of package names
+func splitNames(list string) []string {
+	var names []string
+	for _, name := range strings.Split(list, ",") {
+		if name = strings.TrimSpace(name); len(name) > 0 {
+			names = append(names, name)
+		}
+	}
+	return names
+}
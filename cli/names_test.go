@@ -0,0 +1,74 @@
+//
+// Copyright 2018-2021 Bryan T. Meyers <root@datadrake.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package cli
+
+import (
+	"os"
+	"reflect"
+	"testing"
+)
+
+// withStdin runs f with stdin reading the given text
+func withStdin(t *testing.T, text string, f func()) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("Failed to make a pipe, reason: '%s'", err)
+	}
+	if _, err = w.WriteString(text); err != nil {
+		t.Fatalf("Failed to write to a pipe, reason: '%s'", err)
+	}
+	w.Close()
+	stdin := os.Stdin
+	os.Stdin = r
+	defer func() {
+		os.Stdin = stdin
+		r.Close()
+	}()
+	f()
+}
+
+func TestReadNames(t *testing.T) {
+	tests := []struct {
+		what  string
+		args  []string
+		file  string
+		stdin string
+		names []string
+	}{
+		{"arguments", []string{"zlib", "curl", "zlib"}, "", "", []string{"zlib", "curl"}},
+		{"stdin without arguments", nil, "", "zlib\n# comment\n\ncurl\n", []string{"zlib", "curl"}},
+		{"stdin as the file", []string{"git"}, StdinPath, " zlib \ngit\n", []string{"git", "zlib"}},
+		{"arguments over stdin", []string{"git"}, "", "zlib\n", []string{"git"}},
+	}
+	for _, test := range tests {
+		withStdin(t, test.stdin, func() {
+			names, err := readNames(test.args, test.file)
+			if err != nil {
+				t.Errorf("readNames with %s: unexpected error: %s", test.what, err)
+				return
+			}
+			if !reflect.DeepEqual(names, test.names) {
+				t.Errorf("readNames with %s = %q, expected %q", test.what, names, test.names)
+			}
+		})
+	}
+	withStdin(t, "# nothing\n", func() {
+		if _, err := readNames(nil, StdinPath); err != ErrNoNames {
+			t.Errorf("readNames with no names: expected ErrNoNames, found %v", err)
+		}
+	})
+}
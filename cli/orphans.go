@@ -0,0 +1,134 @@
+//
+// Copyright 2018-2021 Bryan T. Meyers <root@datadrake.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package cli
+
+import (
+	"fmt"
+	"github.com/DataDrake/cli-ng/v2/cmd"
+	"github.com/DataDrake/eopkg-deps/graph"
+	"github.com/DataDrake/eopkg-deps/search"
+	"github.com/DataDrake/eopkg-deps/storage"
+	"os"
+	"os/user"
+)
+
+func init() {
+	cmd.Register(&Orphans)
+}
+
+// Orphans finds packages that nothing depends on and that are not meant to be installed directly
+var Orphans = cmd.Sub{
+	Name:  "orphans",
+	Alias: "or",
+	Short: "Find packages which nothing depends on",
+	Flags: &OrphansFlags{
+		Components: DefaultUserComponents,
+		Layers:     1,
+	},
+	Run: OrphansRun,
+}
+
+// OrphansFlags contains the additional flags for the "orphans" subcommand
+type OrphansFlags struct {
+	Allow         string `short:"a" long:"allow" desc:"comma-separated packages which are not orphans"`
+	AllowFromFile string `short:"A" long:"allow-from-file" desc:"read allowed packages from a file, one per line, or '-' for stdin"`
+	Components    string `short:"c" long:"components" desc:"comma-separated user-facing components, which are not orphans"`
+	Layers        int    `short:"l" long:"layers" desc:"layers of orphans to remove (0 for all)"`
+}
+
+// DefaultUserComponents are the components which are installed directly by users, along with
+// their sub-components
+const DefaultUserComponents = "desktop"
+
+const (
+	// OrphansHeader is a table heading for orphaned packages
+	OrphansHeader = "Orphans, Layer %d\n"
+	// OrphansHeaderColor is a table heading for orphaned packages, in color
+	OrphansHeaderColor = "\033[1mOrphans, Layer %d\n\033[0m"
+)
+
+// OrphansRun carries out the "orphans" subcommand
+func OrphansRun(r *cmd.Root, c *cmd.Sub) {
+	flags := r.Flags.(*GlobalFlags)
+	subFlags := c.Flags.(*OrphansFlags)
+	allowed := make(map[string]bool)
+	for _, name := range splitNames(subFlags.Allow) {
+		allowed[name] = true
+	}
+	if len(subFlags.AllowFromFile) > 0 {
+		names, err := readNamesFile(subFlags.AllowFromFile)
+		if err != nil {
+			fmt.Printf("Failed to read allowed packages, reason: '%s'\n", err.Error())
+			os.Exit(1)
+		}
+		for _, name := range names {
+			allowed[name] = true
+		}
+	}
+	var matchers []search.Matcher
+	for _, pattern := range splitNames(subFlags.Components) {
+		matchers = append(matchers, search.Component(pattern))
+	}
+	userFacing := search.Any(matchers...)
+	ctx, stop := interruptible()
+	defer stop()
+	s := storage.NewStore()
+	curr, err := user.Current()
+	if err != nil {
+		fmt.Printf(UserErrorFormat, err.Error())
+		os.Exit(1)
+	}
+	if err = s.OpenReadOnlyContext(ctx, curr.HomeDir+DefaultDBLocation); err != nil {
+		fmt.Printf(DBOpenErrorFormat, err.Error())
+		os.Exit(1)
+	}
+	defer s.Close()
+	checkIndex(ctx, r, s, curr.HomeDir+DefaultDBLocation)
+	pkgs, deps, err := s.GetGraphContext(ctx)
+	if err != nil {
+		fmt.Printf("Failed to get dependencies, reason: '%s'\n", err.Error())
+		os.Exit(exitCode(err))
+	}
+	stop()
+	layers := graph.New(pkgs, deps).Orphans(subFlags.Layers, func(node *graph.Node) bool {
+		return allowed[node.Name] || userFacing(node.Component)
+	})
+	if len(layers) == 0 {
+		fmt.Printf("No orphans found.\n\n")
+		return
+	}
+	var rowFormat string
+	if flags.NoColor {
+		rowFormat = "%s\n"
+	} else {
+		rowFormat = "\033[0m%s\n"
+	}
+	total := 0
+	for i, layer := range layers {
+		if flags.NoColor {
+			fmt.Printf(OrphansHeader, i+1)
+		} else {
+			fmt.Printf(OrphansHeaderColor, i+1)
+		}
+		for _, node := range layer {
+			fmt.Printf(rowFormat, node.Name)
+		}
+		fmt.Println()
+		total += len(layer)
+	}
+	fmt.Printf("Total: %d\n", total)
+}
@@ -0,0 +1,110 @@
+//
+// Copyright 2018-2021 Bryan T. Meyers <root@datadrake.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"github.com/DataDrake/cli-ng/v2/cmd"
+	"github.com/DataDrake/eopkg-deps/storage"
+	"os"
+	"os/user"
+	"sort"
+	"text/tabwriter"
+)
+
+func init() {
+	cmd.Register(&Provides)
+}
+
+// Provides gets a list of the names a package provides
+var Provides = cmd.Sub{
+	Name:  "provides",
+	Alias: "pr",
+	Short: "Get the names this package provides, like pkg-config modules",
+	Args:  &ProvidesArgs{},
+	Run:   ProvidesRun,
+}
+
+// ProvidesArgs contains the arguments for the "provides" subcommand
+type ProvidesArgs struct {
+	Package string `desc:"the name of the package"`
+}
+
+const (
+	// ProvidesHeader is a table heading for provided names
+	ProvidesHeader = "Provides\tKind\n"
+	// ProvidesHeaderColor is a table heading for provided names, in color
+	ProvidesHeaderColor = "\033[1mProvides\tKind\n"
+)
+
+// ProvidesRun carries out the "provides" subcommand
+func ProvidesRun(r *cmd.Root, c *cmd.Sub) {
+	flags := r.Flags.(*GlobalFlags)
+	args := c.Args.(*ProvidesArgs)
+	ctx, stop := interruptible()
+	defer stop()
+	s := storage.NewStore()
+	curr, err := user.Current()
+	if err != nil {
+		fmt.Printf(UserErrorFormat, err.Error())
+		os.Exit(1)
+	}
+	if err = s.OpenReadOnlyContext(ctx, curr.HomeDir+DefaultDBLocation); err != nil {
+		fmt.Printf(DBOpenErrorFormat, err.Error())
+		os.Exit(1)
+	}
+	defer s.Close()
+	checkIndex(ctx, r, s, curr.HomeDir+DefaultDBLocation)
+	provides, err := s.GetProvidesContext(ctx, args.Package)
+	if errors.Is(err, storage.ErrPackageNotFound) {
+		exitMissing(s, args.Package)
+	}
+	if err != nil {
+		fmt.Printf("Failed to get provides, reason: '%s'\n", err.Error())
+		os.Exit(exitCode(err))
+	}
+	sort.Slice(provides, func(i, j int) bool {
+		if provides[i].Name != provides[j].Name {
+			return provides[i].Name < provides[j].Name
+		}
+		return provides[i].Kind < provides[j].Kind
+	})
+	if flags.NoColor {
+		fmt.Printf(PackageFormat, args.Package)
+	} else {
+		fmt.Printf(PackageFormatColor, args.Package)
+	}
+	if len(provides) == 0 {
+		fmt.Printf("No provides found.\n\n")
+		return
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	var rowFormat string
+	if flags.NoColor {
+		fmt.Fprintf(w, ProvidesHeader)
+		rowFormat = "%s\t%s\n"
+	} else {
+		fmt.Fprintf(w, ProvidesHeaderColor)
+		rowFormat = "\033[0m%s\t%s\n"
+	}
+	for _, provide := range provides {
+		fmt.Fprintf(w, rowFormat, provide.Name, provide.Kind)
+	}
+	w.Flush()
+	fmt.Printf("\nTotal: %d\n", len(provides))
+}
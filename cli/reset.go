@@ -39,20 +39,22 @@ var Reset = cmd.Sub{
 // ResetRun carries out the "reset" subcommand
 func ResetRun(r *cmd.Root, c *cmd.Sub) {
 	//flags := r.Flags.(*GlobalFlags)
+	ctx, stop := interruptible()
+	defer stop()
 	s := storage.NewStore()
 	curr, err := user.Current()
 	if err != nil {
 		fmt.Printf(UserErrorFormat, err.Error())
 		os.Exit(1)
 	}
-	if err = s.Open(curr.HomeDir + DefaultDBLocation); err != nil {
+	if err = s.OpenContext(ctx, curr.HomeDir+DefaultDBLocation); err != nil {
 		fmt.Printf(DBOpenErrorFormat, err.Error())
 		os.Exit(1)
 	}
 	defer s.Close()
-	if err = s.ResetToDo(); err != nil {
+	if err = s.ResetToDoContext(ctx); err != nil {
 		fmt.Printf("Failed to reset ToDo list , reason: '%s'\n", err.Error())
-		os.Exit(1)
+		os.Exit(exitCode(err))
 	}
 	fmt.Println("Successfully marked reset ToDo list")
 }
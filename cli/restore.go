@@ -0,0 +1,77 @@
+//
+// Copyright 2018-2021 Bryan T. Meyers <root@datadrake.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package cli
+
+import (
+	"fmt"
+	"github.com/DataDrake/cli-ng/v2/cmd"
+	"github.com/DataDrake/eopkg-deps/storage"
+	"os"
+	"os/user"
+)
+
+func init() {
+	cmd.Register(&Restore)
+}
+
+// Restore replaces the entire datastore with one written by "dump"
+var Restore = cmd.Sub{
+	Name:  "restore",
+	Alias: "rs",
+	Short: "Replace the datastore with a JSON dump, after checking it is consistent",
+	Args:  &RestoreArgs{},
+	Run:   RestoreRun,
+}
+
+// RestoreArgs contains the arguments for the "restore" subcommand
+type RestoreArgs struct {
+	File string `desc:"a dump written by the 'dump' subcommand"`
+}
+
+// RestoreRun carries out the "restore" subcommand
+func RestoreRun(r *cmd.Root, c *cmd.Sub) {
+	args := c.Args.(*RestoreArgs)
+	f, err := os.Open(args.File)
+	if err != nil {
+		fmt.Printf("Failed to open dump, reason: '%s'\n", err.Error())
+		os.Exit(1)
+	}
+	d, err := storage.ReadDump(f)
+	f.Close()
+	if err != nil {
+		fmt.Printf("Refusing to restore, reason: '%s'\n", err.Error())
+		os.Exit(1)
+	}
+	ctx, stop := interruptible()
+	defer stop()
+	s := storage.NewStore()
+	curr, err := user.Current()
+	if err != nil {
+		fmt.Printf(UserErrorFormat, err.Error())
+		os.Exit(1)
+	}
+	if err = s.OpenContext(ctx, curr.HomeDir+DefaultDBLocation); err != nil {
+		fmt.Printf(DBOpenErrorFormat, err.Error())
+		os.Exit(1)
+	}
+	defer s.Close()
+	if err = s.RestoreContext(ctx, d); err != nil {
+		fmt.Printf("Failed to restore DB, reason: '%s'\n", err.Error())
+		os.Exit(exitCode(err))
+	}
+	fmt.Printf("Restored %d packages, %d dependencies and %d todo entries\n", len(d.Packages), len(d.Dependencies), len(d.ToDo))
+}
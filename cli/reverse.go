@@ -17,7 +17,8 @@
 package cli
 
 import (
-	"database/sql"
+	"context"
+	"errors"
 	"fmt"
 	"github.com/DataDrake/cli-ng/v2/cmd"
 	"github.com/DataDrake/eopkg-deps/storage"
@@ -36,10 +37,17 @@ var Reverse = cmd.Sub{
 	Name:  "reverse",
 	Alias: "rev",
 	Short: "Get this package's reverse dependencies",
+	Flags: &ReverseFlags{},
 	Args:  &ReverseArgs{},
 	Run:   ReverseRun,
 }
 
+// ReverseFlags contains the additional flags for the "reverse" subcommand
+type ReverseFlags struct {
+	ViaProvides bool   `short:"p" long:"via-provides" desc:"treat the package as a provided name, like 'gtk+-3.0'"`
+	Component   string `short:"c" long:"component" desc:"only show packages in these comma-separated components"`
+}
+
 // ReverseArgs contains the arguments for the "reverse" subcommand
 type ReverseArgs struct {
 	Package string `desc:"the name of the package"`
@@ -47,35 +55,71 @@ type ReverseArgs struct {
 
 const (
 	// ReverseDependencyHeader is a table heading for reverse dependencies
-	ReverseDependencyHeader = "Reverse Dependency\tRelease\n"
+	ReverseDependencyHeader = "Reverse Dependency\tRelease\tCurrent\n"
 	// ReverseDependencyHeaderColor is a table heading for reverse dependencies, in color
-	ReverseDependencyHeaderColor = "\033[1mReverse Dependency\tRelease\n"
+	ReverseDependencyHeaderColor = "\033[1mReverse Dependency\tRelease\tCurrent\n"
 )
 
+// getReverseViaProvides gets the reverse dependencies of every package which provides a name
+func getReverseViaProvides(ctx context.Context, s storage.Store, name string) (storage.Packages, error) {
+	providers, err := s.WhatProvidesContext(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	seen := make(map[string]bool)
+	lefts := make(storage.Packages, 0)
+	for _, provider := range providers {
+		revs, err := s.GetReverseContext(ctx, provider.Name)
+		if err != nil {
+			return nil, err
+		}
+		for _, rev := range revs {
+			if !seen[rev.Name] {
+				seen[rev.Name] = true
+				lefts = append(lefts, rev)
+			}
+		}
+	}
+	return lefts, nil
+}
+
 // ReverseRun carries out the "Reverse" subcommand
 func ReverseRun(r *cmd.Root, c *cmd.Sub) {
 	flags := r.Flags.(*GlobalFlags)
+	subFlags := c.Flags.(*ReverseFlags)
 	args := c.Args.(*ReverseArgs)
+	ctx, stop := interruptible()
+	defer stop()
 	s := storage.NewStore()
 	curr, err := user.Current()
 	if err != nil {
 		fmt.Printf(UserErrorFormat, err.Error())
 		os.Exit(1)
 	}
-	if err = s.Open(curr.HomeDir + DefaultDBLocation); err != nil {
+	if err = s.OpenReadOnlyContext(ctx, curr.HomeDir+DefaultDBLocation); err != nil {
 		fmt.Printf(DBOpenErrorFormat, err.Error())
 		os.Exit(1)
 	}
 	defer s.Close()
-	lefts, err := s.GetReverse(args.Package)
-	if err == sql.ErrNoRows {
-		fmt.Printf("Package '%s' does not exist or you need to update\n", args.Package)
-		os.Exit(1)
+	checkIndex(ctx, r, s, curr.HomeDir+DefaultDBLocation)
+	var lefts storage.Packages
+	if subFlags.ViaProvides {
+		lefts, err = getReverseViaProvides(ctx, s, args.Package)
+	} else {
+		lefts, err = s.GetReverseContext(ctx, args.Package)
+	}
+	if errors.Is(err, storage.ErrNotProvided) {
+		fmt.Printf("Nothing provides '%s' or you need to update\n", args.Package)
+		os.Exit(ExitNotFound)
+	}
+	if errors.Is(err, storage.ErrPackageNotFound) {
+		exitMissing(s, args.Package)
 	}
 	if err != nil {
 		fmt.Printf("Failed to resolve reverse deps, reason: '%s'\n", err.Error())
-		os.Exit(1)
+		os.Exit(exitCode(err))
 	}
+	lefts = filterComponents(lefts, subFlags.Component)
 	sort.Sort(lefts)
 	if flags.NoColor {
 		fmt.Printf(PackageFormat, args.Package)
@@ -91,13 +135,13 @@ func ReverseRun(r *cmd.Root, c *cmd.Sub) {
 	var rowFormat string
 	if flags.NoColor {
 		fmt.Fprintf(w, ReverseDependencyHeader)
-		rowFormat = RowFormat
+		rowFormat = ReleaseFormat
 	} else {
 		fmt.Fprintf(w, ReverseDependencyHeaderColor)
-		rowFormat = RowFormatColor
+		rowFormat = ReleaseFormatColor
 	}
 	for _, left := range lefts {
-		fmt.Fprintf(w, rowFormat, left.Name, left.Release)
+		fmt.Fprintf(w, rowFormat, left.Name, left.Release, left.Current)
 	}
 	w.Flush()
 	fmt.Printf("\nTotal: %d\n", len(lefts))
@@ -31,7 +31,8 @@ var Root = &cmd.Root{
 
 // GlobalFlags contains flags applicable to all sub-commands
 type GlobalFlags struct {
-	NoColor bool `short:"N" long:"no-color" desc:"Disable coloring of output text"`
+	NoColor    bool `short:"N" long:"no-color" desc:"Disable coloring of output text"`
+	AutoUpdate bool `short:"u" long:"auto-update" desc:"Update the datastore first if the eopkg index has changed"`
 }
 
 func init() {
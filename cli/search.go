@@ -0,0 +1,135 @@
+//
+// Copyright 2018-2021 Bryan T. Meyers <root@datadrake.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package cli
+
+import (
+	"fmt"
+	"github.com/DataDrake/cli-ng/v2/cmd"
+	"github.com/DataDrake/eopkg-deps/search"
+	"github.com/DataDrake/eopkg-deps/storage"
+	"os"
+	"os/user"
+	"sort"
+	"text/tabwriter"
+)
+
+func init() {
+	cmd.Register(&Search)
+}
+
+// Search finds packages by name or summary
+var Search = cmd.Sub{
+	Name:  "search",
+	Alias: "sr",
+	Short: "Find packages matching a glob, regular expression or fuzzy pattern",
+	Flags: &SearchFlags{},
+	Args:  &SearchArgs{},
+	Run:   SearchRun,
+}
+
+// SearchFlags contains the additional flags for the "search" subcommand
+type SearchFlags struct {
+	Regex   bool `short:"e" long:"regex" desc:"treat the pattern as a regular expression, ignoring case"`
+	Fuzzy   bool `short:"z" long:"fuzzy" desc:"find names which are close to the pattern, of at least 3 characters"`
+	Summary bool `short:"s" long:"summary" desc:"also search package summaries"`
+}
+
+// SearchArgs contains the arguments for the "search" subcommand
+type SearchArgs struct {
+	Pattern string `desc:"a glob like 'lib*-devel', or a plain substring"`
+}
+
+const (
+	// SearchHeader is a table heading for search results
+	SearchHeader = "Package\tSummary\n"
+	// SearchHeaderColor is a table heading for search results, in color
+	SearchHeaderColor = "\033[1mPackage\tSummary\n"
+)
+
+// SearchRun carries out the "search" subcommand
+func SearchRun(r *cmd.Root, c *cmd.Sub) {
+	flags := r.Flags.(*GlobalFlags)
+	subFlags := c.Flags.(*SearchFlags)
+	args := c.Args.(*SearchArgs)
+	var match search.Matcher
+	var err error
+	switch {
+	case subFlags.Regex && subFlags.Fuzzy:
+		fmt.Println("Only one of '--regex' and '--fuzzy' may be used")
+		os.Exit(1)
+	case subFlags.Regex:
+		match, err = search.Regexp(args.Pattern)
+	case subFlags.Fuzzy:
+		match, err = search.Fuzzy(args.Pattern)
+	default:
+		match, err = search.Glob(args.Pattern)
+	}
+	if err != nil {
+		fmt.Printf("Invalid pattern, reason: '%s'\n", err.Error())
+		os.Exit(1)
+	}
+	ctx, stop := interruptible()
+	defer stop()
+	s := storage.NewStore()
+	curr, err := user.Current()
+	if err != nil {
+		fmt.Printf(UserErrorFormat, err.Error())
+		os.Exit(1)
+	}
+	if err = s.OpenReadOnlyContext(ctx, curr.HomeDir+DefaultDBLocation); err != nil {
+		fmt.Printf(DBOpenErrorFormat, err.Error())
+		os.Exit(1)
+	}
+	defer s.Close()
+	checkIndex(ctx, r, s, curr.HomeDir+DefaultDBLocation)
+	pkgs, err := s.GetPackagesContext(ctx)
+	if err != nil {
+		fmt.Printf("Failed to get packages, reason: '%s'\n", err.Error())
+		os.Exit(exitCode(err))
+	}
+	stop()
+	found := make(storage.Packages, 0)
+	for _, pkg := range pkgs {
+		if match(pkg.Name) || (subFlags.Summary && match(pkg.Summary)) {
+			found = append(found, pkg)
+		}
+	}
+	sort.Sort(found)
+	if subFlags.Fuzzy {
+		sort.SliceStable(found, func(i, j int) bool {
+			return search.Distance(args.Pattern, found[i].Name) < search.Distance(args.Pattern, found[j].Name)
+		})
+	}
+	if len(found) == 0 {
+		fmt.Printf("No packages found.\n\n")
+		return
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	var rowFormat string
+	if flags.NoColor {
+		fmt.Fprintf(w, SearchHeader)
+		rowFormat = "%s\t%s\n"
+	} else {
+		fmt.Fprintf(w, SearchHeaderColor)
+		rowFormat = "\033[0m%s\t%s\n"
+	}
+	for _, pkg := range found {
+		fmt.Fprintf(w, rowFormat, pkg.Name, pkg.Summary)
+	}
+	w.Flush()
+	fmt.Printf("\nTotal: %d\n", len(found))
+}
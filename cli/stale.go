@@ -0,0 +1,84 @@
+//
+// Copyright 2018-2021 Bryan T. Meyers <root@datadrake.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package cli
+
+import (
+	"context"
+	"fmt"
+	"github.com/DataDrake/cli-ng/v2/cmd"
+	"github.com/DataDrake/eopkg-deps/index"
+	"github.com/DataDrake/eopkg-deps/storage"
+	"os"
+)
+
+// warn reports a problem on stderr, so that it never ends up mixed into output meant for other tools
+func warn(format string, args ...interface{}) {
+	fmt.Fprintf(os.Stderr, "Warning: "+format+"\n", args...)
+}
+
+// inProgress checks if there is a todo list, which updating the datastore would throw away
+func inProgress(ctx context.Context, s storage.Store) (bool, error) {
+	_, remaining, done, err := s.GetToDoContext(ctx)
+	return remaining+done > 0, err
+}
+
+// checkIndex warns when the eopkg index has changed since the datastore was last updated, or with
+// --auto-update updates it first, as long as that would not throw away a todo list in progress.
+// The index is always the one at DefaultIndexLocation, since that is what an update would read,
+// so a datastore last updated from anywhere else counts as out of date.
+func checkIndex(ctx context.Context, r *cmd.Root, s storage.Store, location string) {
+	flags := r.Flags.(*GlobalFlags)
+	stamp, err := s.GetStampContext(ctx)
+	if err != nil {
+		warn("failed to get index stamp, reason: '%s'", err.Error())
+		return
+	}
+	// Nothing to compare against until the first update since stamps were added, or after a restore
+	if stamp.Path == "" {
+		return
+	}
+	changed, err := index.File(DefaultIndexLocation).Changed(stamp)
+	if err != nil {
+		warn("failed to check index, reason: '%s'", err.Error())
+		return
+	}
+	if !changed {
+		return
+	}
+	if !flags.AutoUpdate {
+		warn("the eopkg index has changed since the last update, run 'update' or use --auto-update")
+		return
+	}
+	w := storage.NewStore()
+	if err = w.OpenContext(ctx, location); err != nil {
+		warn("failed to open DB for updating, reason: '%s'", err.Error())
+		return
+	}
+	defer w.Close()
+	busy, err := inProgress(ctx, w)
+	if err != nil {
+		warn("failed to get todo list, reason: '%s'", err.Error())
+		return
+	}
+	if busy {
+		warn("the eopkg index has changed, but updating would clear the todo list, run 'update' to do so anyway")
+		return
+	}
+	if err = updateFrom(ctx, w, DefaultIndexLocation, os.Stderr); err != nil {
+		warn("failed to update DB, reason: '%s'", err.Error())
+	}
+}
@@ -17,7 +17,6 @@
 package cli
 
 import (
-	"database/sql"
 	"fmt"
 	"github.com/DataDrake/cli-ng/v2/cmd"
 	"github.com/DataDrake/eopkg-deps/storage"
@@ -29,42 +28,60 @@ func init() {
 	cmd.Register(&Start)
 }
 
-// Start marks a package for rebuilds
+// Start marks packages for rebuilds
 var Start = cmd.Sub{
 	Name:  "start",
 	Alias: "to",
-	Short: "Mark a package for rebuilds",
+	Short: "Mark packages for rebuilds, reading stdin if none are listed or with '-f -'",
+	Flags: &StartFlags{},
 	Args:  &StartArgs{},
 	Run:   StartRun,
 }
 
+// StartFlags contains the additional flags for the "start" subcommand
+type StartFlags struct {
+	FromFile string `short:"f" long:"from-file" desc:"read package names from a file, one per line, or '-' for stdin"`
+}
+
 // StartArgs contains the arguments for the "start" subcommand
 type StartArgs struct {
-	Name string `desc:"the name of the package to rebuild"`
+	Names []string `zero:"yes" desc:"the names of the packages to rebuild"`
 }
 
 // StartRun carries out the "start" subcommand
 func StartRun(r *cmd.Root, c *cmd.Sub) {
-	//flags := r.Flags.(*GlobalFlags)
+	flags := c.Flags.(*StartFlags)
 	args := c.Args.(*StartArgs)
+	names, err := readNames(args.Names, flags.FromFile)
+	if err != nil {
+		fmt.Printf("Failed to read package names, reason: '%s'\n", err.Error())
+		os.Exit(1)
+	}
+	ctx, stop := interruptible()
+	defer stop()
 	s := storage.NewStore()
 	curr, err := user.Current()
 	if err != nil {
 		fmt.Printf(UserErrorFormat, err.Error())
 		os.Exit(1)
 	}
-	if err = s.Open(curr.HomeDir + DefaultDBLocation); err != nil {
+	if err = s.OpenContext(ctx, curr.HomeDir+DefaultDBLocation); err != nil {
 		fmt.Printf(DBOpenErrorFormat, err.Error())
 		os.Exit(1)
 	}
 	defer s.Close()
-	if err = s.StartToDo(args.Name); err == sql.ErrNoRows {
-		fmt.Printf("Package '%s' does not exist or you need to update\n", args.Name)
-		os.Exit(1)
+	err = s.StartToDoContext(ctx, names...)
+	if missing, ok := err.(*storage.MissingError); ok {
+		for _, name := range missing.Names {
+			printMissing(s, name)
+		}
+		os.Exit(ExitNotFound)
 	}
 	if err != nil {
 		fmt.Printf("Failed to mark for rebuilds , reason: '%s'\n", err.Error())
-		os.Exit(1)
+		os.Exit(exitCode(err))
+	}
+	for _, name := range names {
+		fmt.Printf("Successfully marked '%s' for rebuilds\n", name)
 	}
-	fmt.Printf("Successfully marked '%s' for rebuilds\n", args.Name)
 }
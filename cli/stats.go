@@ -0,0 +1,127 @@
+//
+// Copyright 2018-2021 Bryan T. Meyers <root@datadrake.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/DataDrake/cli-ng/v2/cmd"
+	"github.com/DataDrake/eopkg-deps/graph"
+	"github.com/DataDrake/eopkg-deps/storage"
+	"os"
+	"os/user"
+	"strings"
+	"text/tabwriter"
+)
+
+func init() {
+	cmd.Register(&Stats)
+}
+
+// Stats reports metrics about the dependencies of the whole repository
+var Stats = cmd.Sub{
+	Name:  "stats",
+	Alias: "st",
+	Short: "Get repository-wide dependency metrics",
+	Flags: &StatsFlags{
+		Top: 10,
+	},
+	Run: StatsRun,
+}
+
+// StatsFlags contains the additional flags for the "stats" subcommand
+type StatsFlags struct {
+	Top  int  `short:"n" long:"top" desc:"number of packages to list in each ranking"`
+	JSON bool `short:"j" long:"json" desc:"print as JSON, including every leaf and root package"`
+}
+
+// printCounts writes out a single ranking as a table
+func printCounts(heading string, counts []graph.Count, noColor bool) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	var rowFormat string
+	if noColor {
+		fmt.Fprintf(w, "%s\tCount\n", heading)
+		rowFormat = RowFormat
+	} else {
+		fmt.Fprintf(w, "\033[1m%s\tCount\n", heading)
+		rowFormat = RowFormatColor
+	}
+	for _, count := range counts {
+		fmt.Fprintf(w, rowFormat, count.Name, count.Count)
+	}
+	w.Flush()
+	fmt.Println()
+}
+
+// StatsRun carries out the "stats" subcommand
+func StatsRun(r *cmd.Root, c *cmd.Sub) {
+	flags := r.Flags.(*GlobalFlags)
+	subFlags := c.Flags.(*StatsFlags)
+	if subFlags.Top < 1 {
+		fmt.Println("The number of packages to list must be at least one")
+		os.Exit(1)
+	}
+	ctx, stop := interruptible()
+	defer stop()
+	s := storage.NewStore()
+	curr, err := user.Current()
+	if err != nil {
+		fmt.Printf(UserErrorFormat, err.Error())
+		os.Exit(1)
+	}
+	if err = s.OpenReadOnlyContext(ctx, curr.HomeDir+DefaultDBLocation); err != nil {
+		fmt.Printf(DBOpenErrorFormat, err.Error())
+		os.Exit(1)
+	}
+	defer s.Close()
+	checkIndex(ctx, r, s, curr.HomeDir+DefaultDBLocation)
+	pkgs, deps, err := s.GetGraphContext(ctx)
+	if err != nil {
+		fmt.Printf("Failed to get dependencies, reason: '%s'\n", err.Error())
+		os.Exit(exitCode(err))
+	}
+	stop()
+	stats := graph.New(pkgs, deps).Stats(subFlags.Top)
+	if subFlags.JSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "    ")
+		if err = enc.Encode(stats); err != nil {
+			fmt.Printf("Failed to encode stats, reason: '%s'\n", err.Error())
+			os.Exit(1)
+		}
+		return
+	}
+	format := "%-13s: %d\n"
+	if !flags.NoColor {
+		format = "\033[0m" + format
+	}
+	fmt.Printf(format, "Packages", stats.Packages)
+	fmt.Printf(format, "Dependencies", stats.Dependencies)
+	fmt.Printf(format, "Leaves", len(stats.Leaves))
+	fmt.Printf(format, "Roots", len(stats.Roots))
+	fmt.Println()
+	printCounts("Most Direct Reverse Deps", stats.MostDirectReverse, flags.NoColor)
+	printCounts("Most Transitive Reverse Deps", stats.MostTransitiveReverse, flags.NoColor)
+	printCounts("Largest Forward Closures", stats.LargestForwardClosures, flags.NoColor)
+	if flags.NoColor {
+		fmt.Printf("Longest Chain (%d)\n", len(stats.LongestChain))
+	} else {
+		fmt.Printf("\033[1mLongest Chain (%d)\n\033[0m", len(stats.LongestChain))
+	}
+	fmt.Println(strings.Join(stats.LongestChain, " <- "))
+	fmt.Println()
+}
@@ -0,0 +1,55 @@
+//
+// Copyright 2018-2021 Bryan T. Meyers <root@datadrake.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package cli
+
+import (
+	"fmt"
+	"github.com/DataDrake/eopkg-deps/search"
+	"github.com/DataDrake/eopkg-deps/storage"
+	"os"
+	"strings"
+)
+
+// SuggestionCount is the most package names suggested in place of a missing one
+const SuggestionCount = 5
+
+// suggest finds the names of packages close to one which does not exist
+func suggest(s storage.Store, name string) []string {
+	pkgs, err := s.GetPackages()
+	if err != nil {
+		return nil
+	}
+	names := make([]string, 0, len(pkgs))
+	for _, pkg := range pkgs {
+		names = append(names, pkg.Name)
+	}
+	return search.Suggest(names, name, SuggestionCount)
+}
+
+// printMissing reports that a package does not exist, along with any close matches
+func printMissing(s storage.Store, name string) {
+	fmt.Printf("Package '%s' does not exist or you need to update\n", name)
+	if suggestions := suggest(s, name); len(suggestions) > 0 {
+		fmt.Printf("    Did you mean: %s\n", strings.Join(suggestions, ", "))
+	}
+}
+
+// exitMissing reports that a package does not exist and exits
+func exitMissing(s storage.Store, name string) {
+	printMissing(s, name)
+	os.Exit(ExitNotFound)
+}
@@ -19,10 +19,12 @@ package cli
 import (
 	"fmt"
 	"github.com/DataDrake/cli-ng/v2/cmd"
+	"github.com/DataDrake/eopkg-deps/graph"
 	"github.com/DataDrake/eopkg-deps/storage"
 	"os"
 	"os/user"
 	"sort"
+	"text/tabwriter"
 )
 
 func init() {
@@ -34,55 +36,140 @@ var ToDo = cmd.Sub{
 	Name:  "todo",
 	Alias: "td",
 	Short: "Get packages to rebuild",
-	Run:   ToDoRun,
+	Flags: &ToDoFlags{
+		Order: OrderName,
+	},
+	Run: ToDoRun,
 }
 
+// ToDoFlags contains the additional flags for the "todo" subcommand
+type ToDoFlags struct {
+	Order     string `short:"o" long:"order" desc:"sort by 'impact', 'depth' or 'name'"`
+	Component string `short:"c" long:"component" desc:"only show packages in these comma-separated components"`
+}
+
+// Orderings for the todo list
+const (
+	// OrderImpact puts the packages with the most queued packages waiting on them first
+	OrderImpact = "impact"
+	// OrderDepth puts the packages with the longest chain of queued packages waiting on them first
+	OrderDepth = "depth"
+	// OrderName sorts packages alphabetically
+	OrderName = "name"
+)
+
 const (
 	// ToDoHeader is a table heading for remaining packages
-	ToDoHeader = "Unblocked Packages"
+	ToDoHeader = "Unblocked Packages\tImpact\tDepth\n"
 	// ToDoHeaderColor is a table heading for remaining packages, in color
-	ToDoHeaderColor = "\033[1mUnblocked Packages"
+	ToDoHeaderColor = "\033[1mUnblocked Packages\tImpact\tDepth\n"
 )
 
+// scored is an unblocked package along with its scores
+type scored struct {
+	name   string
+	impact int
+	depth  int
+}
+
+// scoreToDo calculates the depth of each unblocked package within the queue, taking the impact
+// from the store
+func scoreToDo(unblocked, pending storage.Packages, deps storage.Dependencies) []scored {
+	g := graph.New(pending, deps)
+	depths := g.Remaining(func(*graph.Node) int { return 1 })
+	scores := make([]scored, 0, len(unblocked))
+	for _, pkg := range unblocked {
+		score := scored{name: pkg.Name, impact: pkg.Impact}
+		if node := g.Nodes[pkg.Name]; node != nil {
+			score.depth = depths[node] - 1
+		}
+		scores = append(scores, score)
+	}
+	return scores
+}
+
+// sortToDo orders the scored packages, breaking ties with the other scores and then the name
+func sortToDo(scores []scored, order string) {
+	sort.Slice(scores, func(i, j int) bool {
+		a, b := scores[i], scores[j]
+		switch order {
+		case OrderImpact:
+			if a.impact != b.impact {
+				return a.impact > b.impact
+			}
+			if a.depth != b.depth {
+				return a.depth > b.depth
+			}
+		case OrderDepth:
+			if a.depth != b.depth {
+				return a.depth > b.depth
+			}
+			if a.impact != b.impact {
+				return a.impact > b.impact
+			}
+		}
+		return a.name < b.name
+	})
+}
+
 // ToDoRun carries out the "todo" subcommand
 func ToDoRun(r *cmd.Root, c *cmd.Sub) {
 	flags := r.Flags.(*GlobalFlags)
-	//args := c.Args.(*ToDoArgs)
+	subFlags := c.Flags.(*ToDoFlags)
+	switch subFlags.Order {
+	case OrderImpact, OrderDepth, OrderName:
+	default:
+		fmt.Printf("Order must be one of '%s', '%s' or '%s'\n", OrderImpact, OrderDepth, OrderName)
+		os.Exit(1)
+	}
+	ctx, stop := interruptible()
+	defer stop()
 	s := storage.NewStore()
 	curr, err := user.Current()
 	if err != nil {
 		fmt.Printf(UserErrorFormat, err.Error())
 		os.Exit(1)
 	}
-	if err = s.Open(curr.HomeDir + DefaultDBLocation); err != nil {
+	if err = s.OpenReadOnlyContext(ctx, curr.HomeDir+DefaultDBLocation); err != nil {
 		fmt.Printf(DBOpenErrorFormat, err.Error())
 		os.Exit(1)
 	}
 	defer s.Close()
+	checkIndex(ctx, r, s, curr.HomeDir+DefaultDBLocation)
 	var rowFormat string
 	if flags.NoColor {
-		rowFormat = "%s\n"
+		rowFormat = "%s\t%d\t%d\n"
 	} else {
-		rowFormat = "\033[0m%s\n"
+		rowFormat = "\033[0m%s\t%d\t%d\n"
 	}
-	unblocked, count, done, err := s.GetToDo()
+	var w *tabwriter.Writer
+	unblocked, count, done, err := s.GetToDoContext(ctx)
 	if err != nil {
 		fmt.Printf("Failed to get todo list, reason: '%s'\n", err.Error())
-		os.Exit(1)
+		os.Exit(exitCode(err))
+	}
+	pending, deps, err := s.GetPendingContext(ctx)
+	if err != nil {
+		fmt.Printf("Failed to get todo list, reason: '%s'\n", err.Error())
+		os.Exit(exitCode(err))
 	}
-	sort.Sort(unblocked)
+	unblocked = filterComponents(unblocked, subFlags.Component)
+	scores := scoreToDo(unblocked, pending, deps)
+	sortToDo(scores, subFlags.Order)
 	if len(unblocked) == 0 {
 		fmt.Println("No todo items found.")
 		goto DONE
 	}
+	w = tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
 	if flags.NoColor {
-		fmt.Println(ToDoHeader)
+		fmt.Fprintf(w, ToDoHeader)
 	} else {
-		fmt.Println(ToDoHeaderColor)
+		fmt.Fprintf(w, ToDoHeaderColor)
 	}
-	for _, item := range unblocked {
-		fmt.Printf(rowFormat, item.Name)
+	for _, item := range scores {
+		fmt.Fprintf(w, rowFormat, item.name, item.impact, item.depth)
 	}
+	w.Flush()
 DONE:
 	fmt.Println()
 	if flags.NoColor {
@@ -0,0 +1,238 @@
+//
+// Copyright 2018-2021 Bryan T. Meyers <root@datadrake.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/DataDrake/cli-ng/v2/cmd"
+	"github.com/DataDrake/eopkg-deps/storage"
+	"os"
+	"os/user"
+	"sort"
+)
+
+func init() {
+	cmd.Register(&Tree)
+}
+
+// Tree prints the transitive dependencies of a package as a tree
+var Tree = cmd.Sub{
+	Name:  "tree",
+	Alias: "tr",
+	Short: "Get a tree of this package's transitive dependencies",
+	Flags: &TreeFlags{},
+	Args:  &TreeArgs{},
+	Run:   TreeRun,
+}
+
+// TreeFlags contains the additional flags for the "tree" subcommand
+type TreeFlags struct {
+	Reverse bool `short:"r" long:"reverse" desc:"follow reverse dependencies instead"`
+	Depth   int  `short:"d" long:"depth" desc:"stop after this many levels (0 for no limit)"`
+}
+
+// TreeArgs contains the arguments for the "tree" subcommand
+type TreeArgs struct {
+	Package string `desc:"the name of the package"`
+}
+
+// Tree drawing
+const (
+	TreeBranch     = "|-- "
+	TreeLastBranch = "`-- "
+	TreeIndent     = "|   "
+	TreeLastIndent = "    "
+	TreeSeenMark   = " (*)"
+)
+
+// depTree walks the dependencies of a package, remembering each package's edges
+type depTree struct {
+	ctx     context.Context
+	s       storage.Store
+	reverse bool
+	limit   int
+	edges   map[string]storage.Packages
+	depths  map[string]int
+	shown   map[string]bool
+}
+
+// children gets the sorted dependencies of a package, querying each package once
+func (t *depTree) children(name string) (storage.Packages, error) {
+	if pkgs, ok := t.edges[name]; ok {
+		return pkgs, nil
+	}
+	var pkgs storage.Packages
+	var err error
+	if t.reverse {
+		pkgs, err = t.s.GetReverseContext(t.ctx, name)
+	} else {
+		pkgs, err = t.s.GetForwardContext(t.ctx, name)
+	}
+	if err != nil {
+		return nil, err
+	}
+	sort.Sort(pkgs)
+	t.edges[name] = pkgs
+	return pkgs, nil
+}
+
+// walk finds the shallowest depth at which each package is reached
+func (t *depTree) walk(root string) error {
+	t.depths[root] = 0
+	if t.reverse {
+		return t.walkReverse(root)
+	}
+	level := []string{root}
+	for depth := 1; len(level) > 0; depth++ {
+		if t.limit > 0 && depth > t.limit {
+			break
+		}
+		var next []string
+		for _, name := range level {
+			pkgs, err := t.children(name)
+			if err != nil {
+				return err
+			}
+			for _, pkg := range pkgs {
+				if _, ok := t.depths[pkg.Name]; ok {
+					continue
+				}
+				t.depths[pkg.Name] = depth
+				next = append(next, pkg.Name)
+			}
+		}
+		level = next
+	}
+	return nil
+}
+
+// walkReverse gets the depths from WorstToDo, which reads the reverse closure kept by "update --closure"
+func (t *depTree) walkReverse(root string) error {
+	pkgs, err := t.s.WorstToDoContext(t.ctx, root, t.limit)
+	if err != nil {
+		return err
+	}
+	for _, pkg := range pkgs {
+		// A cycle leads back to the root, which is already shown at the top
+		if pkg.Name != root {
+			t.depths[pkg.Name] = pkg.Depth
+		}
+	}
+	// Drawing still needs the edges of every package which gets expanded
+	for name, depth := range t.depths {
+		if t.limit > 0 && depth >= t.limit {
+			continue
+		}
+		if _, err = t.children(name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// print writes out the tree, expanding each package only at its shallowest occurrence
+func (t *depTree) print(name, prefix string, depth int) {
+	pkgs := t.edges[name]
+	for i, pkg := range pkgs {
+		branch, indent := TreeBranch, TreeIndent
+		if i == len(pkgs)-1 {
+			branch, indent = TreeLastBranch, TreeLastIndent
+		}
+		if t.shown[pkg.Name] || t.depths[pkg.Name] != depth+1 {
+			fmt.Printf("%s%s%s%s\n", prefix, branch, pkg.Name, TreeSeenMark)
+			continue
+		}
+		fmt.Printf("%s%s%s\n", prefix, branch, pkg.Name)
+		t.shown[pkg.Name] = true
+		t.print(pkg.Name, prefix+indent, depth+1)
+	}
+}
+
+// counts gets the number of packages first reached at each depth
+func (t *depTree) counts() []int {
+	var counts []int
+	for _, depth := range t.depths {
+		for len(counts) <= depth {
+			counts = append(counts, 0)
+		}
+		counts[depth]++
+	}
+	return counts
+}
+
+// TreeRun carries out the "tree" subcommand
+func TreeRun(r *cmd.Root, c *cmd.Sub) {
+	flags := r.Flags.(*GlobalFlags)
+	subFlags := c.Flags.(*TreeFlags)
+	args := c.Args.(*TreeArgs)
+	ctx, stop := interruptible()
+	defer stop()
+	s := storage.NewStore()
+	curr, err := user.Current()
+	if err != nil {
+		fmt.Printf(UserErrorFormat, err.Error())
+		os.Exit(1)
+	}
+	if err = s.OpenReadOnlyContext(ctx, curr.HomeDir+DefaultDBLocation); err != nil {
+		fmt.Printf(DBOpenErrorFormat, err.Error())
+		os.Exit(1)
+	}
+	defer s.Close()
+	checkIndex(ctx, r, s, curr.HomeDir+DefaultDBLocation)
+	t := &depTree{
+		ctx:     ctx,
+		s:       s,
+		reverse: subFlags.Reverse,
+		limit:   subFlags.Depth,
+		edges:   make(map[string]storage.Packages),
+		depths:  make(map[string]int),
+		shown:   map[string]bool{args.Package: true},
+	}
+	err = t.walk(args.Package)
+	if errors.Is(err, storage.ErrPackageNotFound) {
+		exitMissing(s, args.Package)
+	}
+	if err != nil {
+		fmt.Printf("Failed to get dependency tree, reason: '%s'\n", err.Error())
+		os.Exit(exitCode(err))
+	}
+	if flags.NoColor {
+		fmt.Printf(PackageFormat, args.Package)
+	} else {
+		fmt.Printf(PackageFormatColor, args.Package)
+	}
+	fmt.Println(args.Package)
+	t.print(args.Package, "", 0)
+	fmt.Println()
+	var depthFormat string
+	if flags.NoColor {
+		fmt.Println(DepthHeader)
+		depthFormat = DepthFormat
+	} else {
+		fmt.Println(DepthHeaderColor)
+		depthFormat = DepthFormatColor
+	}
+	counts := t.counts()
+	for depth := 1; depth < len(counts); depth++ {
+		fmt.Printf(depthFormat, depth, counts[depth])
+	}
+	fmt.Println()
+	fmt.Printf("Total: %d\n", len(t.depths)-1)
+	fmt.Printf("%s already shown at a shallower depth or elsewhere in the tree\n", TreeSeenMark[1:])
+}
@@ -17,12 +17,15 @@
 package cli
 
 import (
+	"context"
 	"fmt"
 	"github.com/DataDrake/cli-ng/v2/cmd"
 	"github.com/DataDrake/eopkg-deps/index"
 	"github.com/DataDrake/eopkg-deps/storage"
+	"io"
 	"os"
 	"os/user"
+	"time"
 )
 
 func init() {
@@ -34,32 +37,143 @@ var Update = cmd.Sub{
 	Name:  "update",
 	Alias: "up",
 	Short: "Update rebuilds the datastore from the eopkg index",
-	Run:   UpdateRun,
+	Flags: &UpdateFlags{
+		Interval: 10,
+	},
+	Run: UpdateRun,
+}
+
+// UpdateFlags contains the additional flags for the "update" subcommand
+type UpdateFlags struct {
+	Closure   bool `short:"c" long:"closure" desc:"precompute the reverse closure of every package for worst, tree and todo, kept on future updates"`
+	NoClosure bool `short:"C" long:"no-closure" desc:"stop keeping the reverse closure"`
+	Watch     bool `short:"w" long:"watch" desc:"keep checking the index and update again whenever it changes, unless there is a todo list"`
+	Interval  int  `short:"i" long:"interval" desc:"seconds between checks of the index when watching"`
+}
+
+// updateFrom rebuilds the datastore from an eopkg index, recording which version of it was used
+// and reporting any problems with the index to out
+func updateFrom(ctx context.Context, s storage.Store, path string, out io.Writer) error {
+	// Linting and stamping along the way keeps it to a single read of the index
+	f := &index.Stamper{File: index.File(path)}
+	l := index.NewLinter()
+	if err := s.UpdateContext(ctx, l.Through(f)); err != nil {
+		return err
+	}
+	// Packages with errors get left out, so those are worth mentioning every time
+	findings := l.Findings()
+	for _, finding := range findings {
+		if finding.Severity == index.Error {
+			fmt.Fprintln(out, finding)
+		}
+	}
+	if warnings := findings.Count(index.Warning); warnings > 0 {
+		fmt.Fprintf(out, "Index has %d warnings, run 'index lint' for details\n", warnings)
+	}
+	return s.SetStampContext(ctx, f.Stamp)
+}
+
+// watch updates the datastore straight away and then every time the eopkg index changes, until
+// interrupted, skipping changes while there is a todo list so that an unattended watch never throws one away
+func watch(ctx context.Context, s storage.Store, path string, interval time.Duration) {
+	// Only mention a skipped change once, rather than on every check
+	skipped := false
+	check := func() {
+		stamp, err := s.GetStampContext(ctx)
+		if err != nil {
+			fmt.Printf("Failed to get index stamp, reason: '%s'\n", err.Error())
+			return
+		}
+		// eopkg may be part way through replacing the index, so failures are worth trying again
+		changed, err := index.File(path).Changed(stamp)
+		if err != nil {
+			fmt.Printf("Failed to check index, reason: '%s'\n", err.Error())
+			return
+		}
+		if !changed {
+			return
+		}
+		busy, err := inProgress(ctx, s)
+		if err != nil {
+			fmt.Printf("Failed to get todo list, reason: '%s'\n", err.Error())
+			return
+		}
+		if busy {
+			if !skipped {
+				fmt.Println("Index has changed, but updating would clear the todo list, skipping until it is reset")
+			}
+			skipped = true
+			return
+		}
+		skipped = false
+		fmt.Println("Index has changed, updating")
+		if err = updateFrom(ctx, s, path, os.Stdout); err != nil {
+			if ctx.Err() == nil {
+				fmt.Printf("Failed to update DB, reason: '%s'\n", err.Error())
+			}
+			return
+		}
+		fmt.Println("Successfully updated")
+	}
+	fmt.Printf("Watching '%s' for changes, press Ctrl+C to stop\n", path)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		check()
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
 }
 
 // UpdateRun carries out the "update" subcommand
 func UpdateRun(r *cmd.Root, c *cmd.Sub) {
 	//args := c.Args.(*RebuildArgs)
-	i := index.NewIndex()
-	err := i.Load(DefaultIndexLocation)
-	if err != nil {
-		fmt.Printf("Failed to load index, reason: '%s'\n", err.Error())
+	flags := c.Flags.(*UpdateFlags)
+	if flags.Closure && flags.NoClosure {
+		fmt.Println("Only one of --closure and --no-closure may be used")
+		os.Exit(1)
+	}
+	if flags.Watch && flags.Interval < 1 {
+		fmt.Println("The interval must be at least one second")
 		os.Exit(1)
 	}
-	//i.Graph()
+	ctx, stop := interruptible()
+	defer stop()
 	s := storage.NewStore()
 	curr, err := user.Current()
 	if err != nil {
 		fmt.Printf(UserErrorFormat, err.Error())
 		os.Exit(1)
 	}
-	if err = s.Open(curr.HomeDir + DefaultDBLocation); err != nil {
+	if err = s.OpenContext(ctx, curr.HomeDir+DefaultDBLocation); err != nil {
 		fmt.Printf(DBOpenErrorFormat, err.Error())
 		os.Exit(1)
 	}
 	defer s.Close()
-	if err = s.Update(i); err != nil {
-		fmt.Printf("Failed to update DB, reason: '%s'\n", err.Error())
-		os.Exit(1)
+	// Dropping the closure first saves building it again during the update
+	if flags.NoClosure {
+		if err = s.SetClosureContext(ctx, false); err != nil {
+			fmt.Printf("Failed to drop closure, reason: '%s'\n", err.Error())
+			os.Exit(exitCode(err))
+		}
+	}
+	// Watching does the first update itself, unless that would clear the todo list
+	if !flags.Watch {
+		if err = updateFrom(ctx, s, DefaultIndexLocation, os.Stdout); err != nil {
+			fmt.Printf("Failed to update DB, reason: '%s'\n", err.Error())
+			os.Exit(exitCode(err))
+		}
+	}
+	if flags.Closure {
+		if err = s.SetClosureContext(ctx, true); err != nil {
+			fmt.Printf("Failed to build closure, reason: '%s'\n", err.Error())
+			os.Exit(exitCode(err))
+		}
+	}
+	if flags.Watch {
+		watch(ctx, s, DefaultIndexLocation, time.Duration(flags.Interval)*time.Second)
 	}
 }
@@ -0,0 +1,94 @@
+//
+// Copyright 2018-2021 Bryan T. Meyers <root@datadrake.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"github.com/DataDrake/cli-ng/v2/cmd"
+	"github.com/DataDrake/eopkg-deps/storage"
+	"os"
+	"os/user"
+	"sort"
+)
+
+func init() {
+	cmd.Register(&WhatProvides)
+}
+
+// WhatProvides gets a list of packages that provide a name, like a pkg-config module
+var WhatProvides = cmd.Sub{
+	Name:  "whatprovides",
+	Alias: "wp",
+	Short: "Get the packages which provide a name, like a pkg-config module",
+	Args:  &WhatProvidesArgs{},
+	Run:   WhatProvidesRun,
+}
+
+// WhatProvidesArgs contains the arguments for the "whatprovides" subcommand
+type WhatProvidesArgs struct {
+	Name string `desc:"the provided name, like 'gtk+-3.0'"`
+}
+
+const (
+	// ProvidersHeader is a table heading for providing packages
+	ProvidersHeader = "Provided By"
+	// ProvidersHeaderColor is a table heading for providing packages, in color
+	ProvidersHeaderColor = "\033[1mProvided By"
+)
+
+// WhatProvidesRun carries out the "whatprovides" subcommand
+func WhatProvidesRun(r *cmd.Root, c *cmd.Sub) {
+	flags := r.Flags.(*GlobalFlags)
+	args := c.Args.(*WhatProvidesArgs)
+	ctx, stop := interruptible()
+	defer stop()
+	s := storage.NewStore()
+	curr, err := user.Current()
+	if err != nil {
+		fmt.Printf(UserErrorFormat, err.Error())
+		os.Exit(1)
+	}
+	if err = s.OpenReadOnlyContext(ctx, curr.HomeDir+DefaultDBLocation); err != nil {
+		fmt.Printf(DBOpenErrorFormat, err.Error())
+		os.Exit(1)
+	}
+	defer s.Close()
+	checkIndex(ctx, r, s, curr.HomeDir+DefaultDBLocation)
+	providers, err := s.WhatProvidesContext(ctx, args.Name)
+	if errors.Is(err, storage.ErrNotProvided) {
+		fmt.Printf("Nothing provides '%s' or you need to update\n", args.Name)
+		os.Exit(ExitNotFound)
+	}
+	if err != nil {
+		fmt.Printf("Failed to get providers, reason: '%s'\n", err.Error())
+		os.Exit(exitCode(err))
+	}
+	sort.Sort(providers)
+	var rowFormat string
+	if flags.NoColor {
+		fmt.Println(ProvidersHeader)
+		rowFormat = "%s\n"
+	} else {
+		fmt.Println(ProvidersHeaderColor)
+		rowFormat = "\033[0m%s\n"
+	}
+	for _, provider := range providers {
+		fmt.Printf(rowFormat, provider.Name)
+	}
+	fmt.Println()
+}
@@ -17,13 +17,14 @@
 package cli
 
 import (
-	"database/sql"
+	"errors"
 	"fmt"
 	"github.com/DataDrake/cli-ng/v2/cmd"
 	"github.com/DataDrake/eopkg-deps/storage"
 	"os"
 	"os/user"
 	"sort"
+	"text/tabwriter"
 )
 
 func init() {
@@ -35,10 +36,19 @@ var Worst = cmd.Sub{
 	Name:  "worst",
 	Alias: "ow",
 	Short: "Calculate the worst-case rebuild list",
+	Flags: &WorstFlags{},
 	Args:  &WorstArgs{},
 	Run:   WorstRun,
 }
 
+// WorstFlags contains the additional flags for the "worst" subcommand
+type WorstFlags struct {
+	MaxDepth        int    `short:"d" long:"max-depth" desc:"stop after this many levels (0 for no limit)"`
+	Exclude         string `short:"x" long:"exclude" desc:"comma-separated packages that never need rebuilding"`
+	ExcludeFromFile string `short:"X" long:"exclude-from-file" desc:"read excluded packages from a file, one per line, or '-' for stdin"`
+	Component       string `short:"c" long:"component" desc:"only show packages in these comma-separated components"`
+}
+
 // WorstArgs contains the arguments for the "worst" subcommand
 type WorstArgs struct {
 	Name string `desc:"the name of the package to rebuild"`
@@ -46,50 +56,81 @@ type WorstArgs struct {
 
 const (
 	// WorstHeader is a table heading for required rebuilds
-	WorstHeader = "Required Rebuilds"
+	WorstHeader = "Required Rebuilds\tDepth\n"
 	// WorstHeaderColor is a table heading for required rebuilds, in color
-	WorstHeaderColor = "\033[1mRequired Rebuilds"
+	WorstHeaderColor = "\033[1mRequired Rebuilds\tDepth\n"
 )
 
 // WorstRun carries out the "worst" subcommand
 func WorstRun(r *cmd.Root, c *cmd.Sub) {
 	flags := r.Flags.(*GlobalFlags)
+	subFlags := c.Flags.(*WorstFlags)
 	args := c.Args.(*WorstArgs)
+	exclude := splitNames(subFlags.Exclude)
+	if len(subFlags.ExcludeFromFile) > 0 {
+		more, err := readNamesFile(subFlags.ExcludeFromFile)
+		if err != nil {
+			fmt.Printf("Failed to read excluded packages, reason: '%s'\n", err.Error())
+			os.Exit(1)
+		}
+		exclude = append(exclude, more...)
+	}
+	ctx, stop := interruptible()
+	defer stop()
 	s := storage.NewStore()
 	curr, err := user.Current()
 	if err != nil {
 		fmt.Printf(UserErrorFormat, err.Error())
 		os.Exit(1)
 	}
-	if err = s.Open(curr.HomeDir + DefaultDBLocation); err != nil {
+	if err = s.OpenReadOnlyContext(ctx, curr.HomeDir+DefaultDBLocation); err != nil {
 		fmt.Printf(DBOpenErrorFormat, err.Error())
 		os.Exit(1)
 	}
 	defer s.Close()
-	list, err := s.WorstToDo(args.Name)
-	if err == sql.ErrNoRows {
-		fmt.Printf("Package '%s' does not exist or you need to update\n", args.Name)
-		os.Exit(1)
+	checkIndex(ctx, r, s, curr.HomeDir+DefaultDBLocation)
+	list, err := s.WorstToDoContext(ctx, args.Name, subFlags.MaxDepth, exclude...)
+	if errors.Is(err, storage.ErrPackageNotFound) {
+		exitMissing(s, args.Name)
 	}
 	if err != nil {
 		fmt.Printf("Failed to get todo list, reason: '%s'\n", err.Error())
-		os.Exit(1)
+		os.Exit(exitCode(err))
 	}
+	list = filterComponents(list, subFlags.Component)
 	if len(list) == 0 {
 		fmt.Printf("No todo items found.\n\n")
 		return
 	}
 	sort.Sort(list)
-	var rowFormat string
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	var rowFormat, depthFormat string
 	if flags.NoColor {
-		fmt.Println(WorstHeader)
-		rowFormat = "%s\n"
+		fmt.Fprintf(w, WorstHeader)
+		rowFormat = RowFormat
+		depthFormat = DepthFormat
 	} else {
-		fmt.Println(WorstHeaderColor)
-		rowFormat = "\033[0m%s\n"
+		fmt.Fprintf(w, WorstHeaderColor)
+		rowFormat = RowFormatColor
+		depthFormat = DepthFormatColor
 	}
+	var counts []int
 	for _, item := range list {
-		fmt.Printf(rowFormat, item.Name)
+		fmt.Fprintf(w, rowFormat, item.Name, item.Depth)
+		for len(counts) <= item.Depth {
+			counts = append(counts, 0)
+		}
+		counts[item.Depth]++
+	}
+	w.Flush()
+	fmt.Println()
+	if flags.NoColor {
+		fmt.Println(DepthHeader)
+	} else {
+		fmt.Println(DepthHeaderColor)
+	}
+	for depth := 1; depth < len(counts); depth++ {
+		fmt.Printf(depthFormat, depth, counts[depth])
 	}
 	fmt.Println()
 	if flags.NoColor {
@@ -0,0 +1,80 @@
+//
+// Copyright 2018-2021 Bryan T. Meyers <root@datadrake.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package graph
+
+import (
+	"sort"
+	"strings"
+)
+
+// ComponentLink counts the dependencies of the packages in one component on those in another
+type ComponentLink struct {
+	From string
+	To   string
+	// Packages is the number of packages in From which depend on something in To
+	Packages int
+	// Dependencies is the total number of dependencies from From to To
+	Dependencies int
+}
+
+// TruncateComponent keeps only the first levels parts of a component, or all of them if zero
+func TruncateComponent(component string, levels int) string {
+	if levels <= 0 {
+		return component
+	}
+	parts := strings.SplitN(component, ".", levels+1)
+	if len(parts) > levels {
+		parts = parts[:levels]
+	}
+	return strings.Join(parts, ".")
+}
+
+// Components counts the packages in each component and the dependencies between components,
+// grouping components by their first levels parts unless zero
+func (g *Graph) Components(levels int) (map[string]int, []ComponentLink) {
+	sizes := make(map[string]int)
+	links := make(map[[2]string]*ComponentLink)
+	for _, node := range g.Sorted() {
+		from := TruncateComponent(node.Component, levels)
+		sizes[from]++
+		counted := make(map[string]bool)
+		for _, dep := range node.Deps {
+			to := TruncateComponent(dep.Component, levels)
+			link := links[[2]string{from, to}]
+			if link == nil {
+				link = &ComponentLink{From: from, To: to}
+				links[[2]string{from, to}] = link
+			}
+			link.Dependencies++
+			if !counted[to] {
+				counted[to] = true
+				link.Packages++
+			}
+		}
+	}
+	list := make([]ComponentLink, 0, len(links))
+	for _, link := range links {
+		list = append(list, *link)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].From != list[j].From {
+			return list[i].From < list[j].From
+		}
+		return list[i].To < list[j].To
+	})
+	return sizes, list
+}
@@ -0,0 +1,148 @@
+//
+// Copyright 2018-2021 Bryan T. Meyers <root@datadrake.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package graph
+
+import (
+	"github.com/DataDrake/eopkg-deps/storage"
+	"sort"
+)
+
+// Node is a single package in a Graph
+type Node struct {
+	Name      string
+	Release   int
+	Component string
+	// Duration is the expected build time, in seconds
+	Duration int
+	// Deps are the packages this package depends on
+	Deps []*Node
+	// Revs are the packages which depend on this package
+	Revs []*Node
+}
+
+// Graph is an in-memory dependency graph for a set of packages
+type Graph struct {
+	Nodes map[string]*Node
+}
+
+// dependsOn checks if this Node already has a dependency on another
+func (n *Node) dependsOn(other *Node) bool {
+	for _, dep := range n.Deps {
+		if dep == other {
+			return true
+		}
+	}
+	return false
+}
+
+// New builds a Graph from a list of packages and the dependencies between them,
+// ignoring duplicates and any dependency on a package which is not in the list
+func New(pkgs storage.Packages, deps storage.Dependencies) *Graph {
+	g := &Graph{
+		Nodes: make(map[string]*Node),
+	}
+	for _, pkg := range pkgs {
+		g.Nodes[pkg.Name] = &Node{
+			Name:      pkg.Name,
+			Release:   pkg.Release,
+			Component: pkg.Component,
+			Duration:  pkg.Duration,
+		}
+	}
+	for _, dep := range deps {
+		left, right := g.Nodes[dep.Left], g.Nodes[dep.Right]
+		if left == nil || right == nil || left == right || left.dependsOn(right) {
+			continue
+		}
+		left.Deps = append(left.Deps, right)
+		right.Revs = append(right.Revs, left)
+	}
+	return g
+}
+
+// Sorted returns every Node in the Graph, ordered by name
+func (g *Graph) Sorted() []*Node {
+	nodes := make([]*Node, 0, len(g.Nodes))
+	for _, node := range g.Nodes {
+		nodes = append(nodes, node)
+	}
+	sort.Slice(nodes, func(i, j int) bool {
+		return nodes[i].Name < nodes[j].Name
+	})
+	return nodes
+}
+
+// Closure finds every other Node reachable by following Deps, or Revs when reverse is set
+func (n *Node) Closure(reverse bool) []*Node {
+	seen := map[*Node]bool{n: true}
+	var found []*Node
+	next := []*Node{n}
+	for len(next) > 0 {
+		node := next[0]
+		next = next[1:]
+		edges := node.Deps
+		if reverse {
+			edges = node.Revs
+		}
+		for _, edge := range edges {
+			if seen[edge] {
+				continue
+			}
+			seen[edge] = true
+			found = append(found, edge)
+			next = append(next, edge)
+		}
+	}
+	return found
+}
+
+// Order returns every Node such that each comes after all of its dependencies.
+// Cycles are broken by ignoring the unfinished dependencies of the first remaining Node by name.
+func (g *Graph) Order() []*Node {
+	waiting := make(map[*Node]int)
+	var ready []*Node
+	for _, node := range g.Sorted() {
+		waiting[node] = len(node.Deps)
+		if len(node.Deps) == 0 {
+			ready = append(ready, node)
+		}
+	}
+	order := make([]*Node, 0, len(g.Nodes))
+	done := make(map[*Node]bool)
+	remaining := g.Sorted()
+	for len(order) < len(g.Nodes) {
+		if len(ready) == 0 {
+			for done[remaining[0]] {
+				remaining = remaining[1:]
+			}
+			ready = append(ready, remaining[0])
+		}
+		node := ready[0]
+		ready = ready[1:]
+		if done[node] {
+			continue
+		}
+		done[node] = true
+		order = append(order, node)
+		for _, rev := range node.Revs {
+			if waiting[rev]--; waiting[rev] == 0 && !done[rev] {
+				ready = append(ready, rev)
+			}
+		}
+	}
+	return order
+}
@@ -0,0 +1,133 @@
+//
+// Copyright 2018-2021 Bryan T. Meyers <root@datadrake.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package graph
+
+import (
+	"github.com/DataDrake/eopkg-deps/storage"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+// build makes a Graph of packages with the given durations, where each dep is a pair of
+// the package which depends and the package it depends on
+func build(durations map[string]int, deps ...[2]string) *Graph {
+	pkgs := make(storage.Packages, 0, len(durations))
+	for name, duration := range durations {
+		pkgs = append(pkgs, storage.Package{Name: name, Duration: duration})
+	}
+	sort.Sort(pkgs)
+	edges := make(storage.Dependencies, 0, len(deps))
+	for _, dep := range deps {
+		edges = append(edges, storage.Dependency{Left: dep[0], Right: dep[1]})
+	}
+	return New(pkgs, edges)
+}
+
+// names lists the names of some Nodes, keeping their order
+func names(nodes []*Node) []string {
+	found := make([]string, 0, len(nodes))
+	for _, node := range nodes {
+		found = append(found, node.Name)
+	}
+	return found
+}
+
+// Graphs shared between tests, with durations for the plans
+var (
+	empty = func() *Graph { return build(nil) }
+	// chain: c needs b, which needs a
+	chain = func() *Graph {
+		return build(map[string]int{"a": 10, "b": 10, "c": 10}, [2]string{"c", "b"}, [2]string{"b", "a"})
+	}
+	// diamond: d needs b and c, which both need a
+	diamond = func() *Graph {
+		return build(map[string]int{"a": 10, "b": 5, "c": 20, "d": 1},
+			[2]string{"d", "b"}, [2]string{"d", "c"}, [2]string{"b", "a"}, [2]string{"c", "a"})
+	}
+	// cycle: a and b need each other, and c needs a
+	cycle = func() *Graph {
+		return build(map[string]int{"a": 5, "b": 5, "c": 5}, [2]string{"a", "b"}, [2]string{"b", "a"}, [2]string{"c", "a"})
+	}
+)
+
+func TestNew(t *testing.T) {
+	g := build(map[string]int{"a": 0, "b": 0},
+		[2]string{"b", "a"}, [2]string{"b", "a"}, [2]string{"a", "a"}, [2]string{"b", "missing"}, [2]string{"missing", "a"})
+	if len(g.Nodes) != 2 {
+		t.Fatalf("New: expected 2 nodes, found %d", len(g.Nodes))
+	}
+	if deps := names(g.Nodes["b"].Deps); !reflect.DeepEqual(deps, []string{"a"}) {
+		t.Errorf("New: expected 'b' to only depend on 'a', found %q", deps)
+	}
+	if revs := names(g.Nodes["a"].Revs); !reflect.DeepEqual(revs, []string{"b"}) {
+		t.Errorf("New: expected only 'b' to depend on 'a', found %q", revs)
+	}
+	if deps := names(g.Nodes["a"].Deps); len(deps) != 0 {
+		t.Errorf("New: expected 'a' to have no dependencies, found %q", deps)
+	}
+}
+
+func TestClosure(t *testing.T) {
+	tests := []struct {
+		g       *Graph
+		name    string
+		reverse bool
+		closure []string
+	}{
+		{chain(), "a", true, []string{"b", "c"}},
+		{chain(), "a", false, []string{}},
+		{chain(), "c", false, []string{"a", "b"}},
+		{diamond(), "a", true, []string{"b", "c", "d"}},
+		{diamond(), "d", false, []string{"a", "b", "c"}},
+		{cycle(), "a", true, []string{"b", "c"}},
+		{cycle(), "b", false, []string{"a"}},
+	}
+	for _, test := range tests {
+		closure := names(test.g.Nodes[test.name].Closure(test.reverse))
+		sort.Strings(closure)
+		if !reflect.DeepEqual(closure, test.closure) {
+			t.Errorf("Closure of '%s' (reverse %t) = %q, expected %q", test.name, test.reverse, closure, test.closure)
+		}
+	}
+}
+
+func TestOrder(t *testing.T) {
+	tests := []struct {
+		what  string
+		g     *Graph
+		order []string
+	}{
+		{"empty", empty(), []string{}},
+		{"independent", build(map[string]int{"y": 0, "x": 0, "z": 0}), []string{"x", "y", "z"}},
+		{"chain", chain(), []string{"a", "b", "c"}},
+		{"diamond", diamond(), []string{"a", "b", "c", "d"}},
+		{"reversed names", build(map[string]int{"a": 0, "b": 0}, [2]string{"a", "b"}), []string{"b", "a"}},
+		// Nothing is ready, so the cycle is broken at 'a', the first remaining by name
+		{"cycle", cycle(), []string{"a", "b", "c"}},
+		{"cycle after its dependency", build(map[string]int{"a": 0, "b": 0, "z": 0},
+			[2]string{"a", "b"}, [2]string{"b", "a"}, [2]string{"a", "z"}), []string{"z", "a", "b"}},
+		{"two cycles", build(map[string]int{"a": 0, "b": 0, "c": 0, "d": 0},
+			[2]string{"a", "b"}, [2]string{"b", "a"}, [2]string{"c", "d"}, [2]string{"d", "c"}, [2]string{"c", "b"}),
+			[]string{"a", "b", "c", "d"}},
+	}
+	for _, test := range tests {
+		if order := names(test.g.Order()); !reflect.DeepEqual(order, test.order) {
+			t.Errorf("Order of %s = %q, expected %q", test.what, order, test.order)
+		}
+	}
+}
@@ -0,0 +1,51 @@
+//
+// Copyright 2018-2021 Bryan T. Meyers <root@datadrake.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package graph
+
+// Orphans finds packages which nothing depends on, skipping any which should be kept.
+// Each following layer contains the packages which only the earlier layers depended on,
+// as if those had been removed. A limit of zero keeps going until nothing else is found.
+func (g *Graph) Orphans(limit int, keep func(*Node) bool) [][]*Node {
+	removed := make(map[*Node]bool)
+	var layers [][]*Node
+	for limit <= 0 || len(layers) < limit {
+		var layer []*Node
+		for _, node := range g.Sorted() {
+			if removed[node] || keep(node) {
+				continue
+			}
+			orphan := true
+			for _, rev := range node.Revs {
+				if !removed[rev] {
+					orphan = false
+					break
+				}
+			}
+			if orphan {
+				layer = append(layer, node)
+			}
+		}
+		if len(layer) == 0 {
+			break
+		}
+		for _, node := range layer {
+			removed[node] = true
+		}
+		layers = append(layers, layer)
+	}
+	return layers
+}
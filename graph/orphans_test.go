@@ -0,0 +1,68 @@
+//
+// Copyright 2018-2021 Bryan T. Meyers <root@datadrake.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package graph
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestOrphans(t *testing.T) {
+	// app needs lib, which needs base, and tool needs base
+	stack := func() *Graph {
+		return build(map[string]int{"app": 0, "base": 0, "lib": 0, "tool": 0},
+			[2]string{"app", "lib"}, [2]string{"lib", "base"}, [2]string{"tool", "base"})
+	}
+	keep := func(names ...string) func(*Node) bool {
+		return func(node *Node) bool {
+			for _, name := range names {
+				if node.Name == name {
+					return true
+				}
+			}
+			return false
+		}
+	}
+	tests := []struct {
+		what   string
+		g      *Graph
+		limit  int
+		keep   func(*Node) bool
+		layers [][]string
+	}{
+		{"empty", empty(), 0, keep(), [][]string{}},
+		{"every layer", stack(), 0, keep(), [][]string{{"app", "tool"}, {"lib"}, {"base"}}},
+		{"one layer", stack(), 1, keep(), [][]string{{"app", "tool"}}},
+		{"two layers", stack(), 2, keep(), [][]string{{"app", "tool"}, {"lib"}}},
+		{"more layers than exist", stack(), 10, keep(), [][]string{{"app", "tool"}, {"lib"}, {"base"}}},
+		{"negative limit", stack(), -1, keep(), [][]string{{"app", "tool"}, {"lib"}, {"base"}}},
+		{"kept leaf", stack(), 0, keep("app"), [][]string{{"tool"}}},
+		{"kept dependency", stack(), 0, keep("lib"), [][]string{{"app", "tool"}}},
+		{"everything kept", stack(), 0, keep("app", "base", "lib", "tool"), [][]string{}},
+		// Packages in a cycle always depend on each other, so only what depends on the cycle goes
+		{"cycle", cycle(), 0, keep(), [][]string{{"c"}}},
+	}
+	for _, test := range tests {
+		layers := make([][]string, 0)
+		for _, layer := range test.g.Orphans(test.limit, test.keep) {
+			layers = append(layers, names(layer))
+		}
+		if !reflect.DeepEqual(layers, test.layers) {
+			t.Errorf("Orphans of %s with limit %d = %q, expected %q", test.what, test.limit, layers, test.layers)
+		}
+	}
+}
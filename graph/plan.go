@@ -0,0 +1,140 @@
+//
+// Copyright 2018-2021 Bryan T. Meyers <root@datadrake.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package graph
+
+import (
+	"container/heap"
+	"sort"
+)
+
+// Remaining finds the longest total weight of any chain of reverse dependencies
+// which starts at each Node, including the Node itself
+func (g *Graph) Remaining(weight func(*Node) int) map[*Node]int {
+	order := g.Order()
+	remaining := make(map[*Node]int)
+	for i := len(order) - 1; i >= 0; i-- {
+		node := order[i]
+		longest := 0
+		for _, rev := range node.Revs {
+			// reverse deps which have not been seen yet belong to a broken cycle
+			if value, ok := remaining[rev]; ok && value > longest {
+				longest = value
+			}
+		}
+		remaining[node] = weight(node) + longest
+	}
+	return remaining
+}
+
+// CriticalPath finds the chain of dependencies with the longest total Duration,
+// returning it in build order along with that total
+func (g *Graph) CriticalPath() ([]*Node, int) {
+	finish := make(map[*Node]int)
+	prev := make(map[*Node]*Node)
+	var last *Node
+	for _, node := range g.Order() {
+		start := 0
+		for _, dep := range node.Deps {
+			if value, ok := finish[dep]; ok && value > start {
+				start = value
+				prev[node] = dep
+			}
+		}
+		finish[node] = start + node.Duration
+		if last == nil || finish[node] > finish[last] {
+			last = node
+		}
+	}
+	var path []*Node
+	for node := last; node != nil; node = prev[node] {
+		path = append([]*Node{node}, path...)
+	}
+	return path, finish[last]
+}
+
+// running is a min-heap of Nodes being built, ordered by when they will finish
+type running struct {
+	nodes  []*Node
+	finish map[*Node]int
+}
+
+func (r *running) Len() int           { return len(r.nodes) }
+func (r *running) Less(i, j int) bool { return r.finish[r.nodes[i]] < r.finish[r.nodes[j]] }
+func (r *running) Swap(i, j int)      { r.nodes[i], r.nodes[j] = r.nodes[j], r.nodes[i] }
+func (r *running) Push(x interface{}) { r.nodes = append(r.nodes, x.(*Node)) }
+func (r *running) Pop() interface{} {
+	last := r.nodes[len(r.nodes)-1]
+	r.nodes = r.nodes[:len(r.nodes)-1]
+	return last
+}
+
+// Schedule simulates building every Node with a fixed number of parallel builders,
+// always starting the ready Node with the most remaining work behind it first,
+// and returns the total time taken
+func (g *Graph) Schedule(builders int) int {
+	if builders < 1 {
+		builders = 1
+	}
+	priority := g.Remaining(func(n *Node) int { return n.Duration })
+	waiting := make(map[*Node]int)
+	var ready []*Node
+	for _, node := range g.Sorted() {
+		waiting[node] = len(node.Deps)
+		if len(node.Deps) == 0 {
+			ready = append(ready, node)
+		}
+	}
+	r := &running{finish: make(map[*Node]int)}
+	started := make(map[*Node]bool)
+	now, built := 0, 0
+	for built < len(g.Nodes) {
+		if len(ready) == 0 && r.Len() == 0 {
+			// everything left is waiting on a cycle, so force the first one to start
+			for _, node := range g.Sorted() {
+				if !started[node] {
+					ready = append(ready, node)
+					break
+				}
+			}
+		}
+		sort.SliceStable(ready, func(i, j int) bool {
+			return priority[ready[i]] > priority[ready[j]]
+		})
+		for len(ready) > 0 && r.Len() < builders {
+			node := ready[0]
+			ready = ready[1:]
+			if started[node] {
+				continue
+			}
+			started[node] = true
+			r.finish[node] = now + node.Duration
+			heap.Push(r, node)
+		}
+		if r.Len() == 0 {
+			continue
+		}
+		node := heap.Pop(r).(*Node)
+		now = r.finish[node]
+		built++
+		for _, rev := range node.Revs {
+			if waiting[rev]--; waiting[rev] == 0 && !started[rev] {
+				ready = append(ready, rev)
+			}
+		}
+	}
+	return now
+}
@@ -0,0 +1,107 @@
+//
+// Copyright 2018-2021 Bryan T. Meyers <root@datadrake.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package graph
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestRemaining(t *testing.T) {
+	tests := []struct {
+		what      string
+		g         *Graph
+		remaining map[string]int
+	}{
+		{"empty", empty(), map[string]int{}},
+		{"chain", chain(), map[string]int{"a": 3, "b": 2, "c": 1}},
+		{"diamond", diamond(), map[string]int{"a": 3, "b": 2, "c": 2, "d": 1}},
+		// 'a' is ordered first, so the edge back to it from 'b' is ignored
+		{"cycle", cycle(), map[string]int{"a": 2, "b": 1, "c": 1}},
+	}
+	for _, test := range tests {
+		remaining := make(map[string]int)
+		for node, value := range test.g.Remaining(func(*Node) int { return 1 }) {
+			remaining[node.Name] = value
+		}
+		if !reflect.DeepEqual(remaining, test.remaining) {
+			t.Errorf("Remaining of %s = %v, expected %v", test.what, remaining, test.remaining)
+		}
+	}
+	durations := make(map[string]int)
+	for node, value := range diamond().Remaining(func(n *Node) int { return n.Duration }) {
+		durations[node.Name] = value
+	}
+	if expected := map[string]int{"a": 31, "b": 6, "c": 21, "d": 1}; !reflect.DeepEqual(durations, expected) {
+		t.Errorf("Remaining durations of diamond = %v, expected %v", durations, expected)
+	}
+}
+
+func TestCriticalPath(t *testing.T) {
+	tests := []struct {
+		what  string
+		g     *Graph
+		path  []string
+		total int
+	}{
+		{"empty", empty(), []string{}, 0},
+		{"single", build(map[string]int{"a": 7}), []string{"a"}, 7},
+		{"chain", chain(), []string{"a", "b", "c"}, 30},
+		{"diamond", diamond(), []string{"a", "c", "d"}, 31},
+		{"longer alone", build(map[string]int{"a": 10, "b": 10, "x": 100}, [2]string{"b", "a"}), []string{"x"}, 100},
+		{"ties", build(map[string]int{"a": 10, "b": 10, "c": 1}, [2]string{"c", "a"}, [2]string{"c", "b"}), []string{"a", "c"}, 11},
+		{"cycle", cycle(), []string{"a", "b"}, 10},
+	}
+	for _, test := range tests {
+		path, total := test.g.CriticalPath()
+		if found := names(path); !reflect.DeepEqual(found, test.path) || total != test.total {
+			t.Errorf("CriticalPath of %s = %q, %d, expected %q, %d", test.what, found, total, test.path, test.total)
+		}
+	}
+}
+
+func TestSchedule(t *testing.T) {
+	independent := func() *Graph { return build(map[string]int{"a": 10, "b": 10, "c": 10}) }
+	// 'p' has the most work waiting on it, so it has to start before 'r' and 's'
+	priority := func() *Graph {
+		return build(map[string]int{"p": 1, "q": 10, "r": 5, "s": 5}, [2]string{"q", "p"})
+	}
+	tests := []struct {
+		what     string
+		g        *Graph
+		builders int
+		total    int
+	}{
+		{"empty", empty(), 4, 0},
+		{"independent, one builder", independent(), 1, 30},
+		{"independent, two builders", independent(), 2, 20},
+		{"independent, spare builders", independent(), 8, 10},
+		{"no builders", independent(), 0, 30},
+		{"negative builders", independent(), -2, 30},
+		{"chain", chain(), 4, 30},
+		{"diamond, one builder", diamond(), 1, 36},
+		{"diamond, two builders", diamond(), 2, 31},
+		{"priority", priority(), 2, 11},
+		{"cycle", cycle(), 1, 15},
+		{"cycle, spare builders", cycle(), 4, 10},
+	}
+	for _, test := range tests {
+		if total := test.g.Schedule(test.builders); total != test.total {
+			t.Errorf("Schedule of %s with %d builders = %d, expected %d", test.what, test.builders, total, test.total)
+		}
+	}
+}
@@ -0,0 +1,109 @@
+//
+// Copyright 2018-2021 Bryan T. Meyers <root@datadrake.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package graph
+
+import (
+	"sort"
+)
+
+// Count is the size of some measurement for a single package
+type Count struct {
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+// Stats are the metrics for an entire Graph
+type Stats struct {
+	Packages               int      `json:"packages"`
+	Dependencies           int      `json:"dependencies"`
+	MostDirectReverse      []Count  `json:"most_direct_reverse"`
+	MostTransitiveReverse  []Count  `json:"most_transitive_reverse"`
+	LargestForwardClosures []Count  `json:"largest_forward_closures"`
+	LongestChain           []string `json:"longest_chain"`
+	Leaves                 []string `json:"leaves"`
+	Roots                  []string `json:"roots"`
+}
+
+// top sorts the counts from largest to smallest and keeps at most n of them
+func top(counts []Count, n int) []Count {
+	sort.SliceStable(counts, func(i, j int) bool {
+		return counts[i].Count > counts[j].Count
+	})
+	if n < 0 {
+		n = 0
+	}
+	if len(counts) > n {
+		counts = counts[:n]
+	}
+	return counts
+}
+
+// LongestChain finds the longest chain of dependencies, starting from the package
+// which everything else in the chain depends on
+func (g *Graph) LongestChain() []*Node {
+	lengths := g.Remaining(func(*Node) int { return 1 })
+	var node *Node
+	for _, candidate := range g.Sorted() {
+		if node == nil || lengths[candidate] > lengths[node] {
+			node = candidate
+		}
+	}
+	var chain []*Node
+	for node != nil {
+		chain = append(chain, node)
+		var next *Node
+		for _, rev := range node.Revs {
+			if lengths[rev] == lengths[node]-1 && (next == nil || rev.Name < next.Name) {
+				next = rev
+			}
+		}
+		node = next
+	}
+	return chain
+}
+
+// Stats calculates the metrics for this Graph, keeping the n largest of each ranking
+func (g *Graph) Stats(n int) *Stats {
+	stats := &Stats{
+		Packages:     len(g.Nodes),
+		Leaves:       make([]string, 0),
+		Roots:        make([]string, 0),
+		LongestChain: make([]string, 0),
+	}
+	direct := make([]Count, 0, len(g.Nodes))
+	transitive := make([]Count, 0, len(g.Nodes))
+	forward := make([]Count, 0, len(g.Nodes))
+	for _, node := range g.Sorted() {
+		stats.Dependencies += len(node.Deps)
+		direct = append(direct, Count{node.Name, len(node.Revs)})
+		transitive = append(transitive, Count{node.Name, len(node.Closure(true))})
+		forward = append(forward, Count{node.Name, len(node.Closure(false))})
+		if len(node.Revs) == 0 {
+			stats.Leaves = append(stats.Leaves, node.Name)
+		}
+		if len(node.Deps) == 0 {
+			stats.Roots = append(stats.Roots, node.Name)
+		}
+	}
+	stats.MostDirectReverse = top(direct, n)
+	stats.MostTransitiveReverse = top(transitive, n)
+	stats.LargestForwardClosures = top(forward, n)
+	for _, node := range g.LongestChain() {
+		stats.LongestChain = append(stats.LongestChain, node.Name)
+	}
+	return stats
+}
@@ -0,0 +1,110 @@
+//
+// Copyright 2018-2021 Bryan T. Meyers <root@datadrake.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package graph
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestLongestChain(t *testing.T) {
+	tests := []struct {
+		what  string
+		g     *Graph
+		chain []string
+	}{
+		{"empty", empty(), []string{}},
+		{"single", build(map[string]int{"a": 0}), []string{"a"}},
+		{"independent", build(map[string]int{"b": 0, "a": 0}), []string{"a"}},
+		{"chain", chain(), []string{"a", "b", "c"}},
+		{"diamond", diamond(), []string{"a", "b", "d"}},
+		{"ties", build(map[string]int{"a": 0, "c": 0, "b": 0}, [2]string{"c", "a"}, [2]string{"b", "a"}), []string{"a", "b"}},
+		{"cycle", cycle(), []string{"a", "b"}},
+	}
+	for _, test := range tests {
+		if chain := names(test.g.LongestChain()); !reflect.DeepEqual(chain, test.chain) {
+			t.Errorf("LongestChain of %s = %q, expected %q", test.what, chain, test.chain)
+		}
+	}
+}
+
+func TestStats(t *testing.T) {
+	// d and b need a, and c needs b
+	g := build(map[string]int{"a": 0, "b": 0, "c": 0, "d": 0}, [2]string{"b", "a"}, [2]string{"c", "b"}, [2]string{"d", "a"})
+	tests := []struct {
+		n     int
+		stats Stats
+	}{
+		{2, Stats{
+			Packages:               4,
+			Dependencies:           3,
+			MostDirectReverse:      []Count{{"a", 2}, {"b", 1}},
+			MostTransitiveReverse:  []Count{{"a", 3}, {"b", 1}},
+			LargestForwardClosures: []Count{{"c", 2}, {"b", 1}},
+			LongestChain:           []string{"a", "b", "c"},
+			Leaves:                 []string{"c", "d"},
+			Roots:                  []string{"a"},
+		}},
+		{10, Stats{
+			Packages:               4,
+			Dependencies:           3,
+			MostDirectReverse:      []Count{{"a", 2}, {"b", 1}, {"c", 0}, {"d", 0}},
+			MostTransitiveReverse:  []Count{{"a", 3}, {"b", 1}, {"c", 0}, {"d", 0}},
+			LargestForwardClosures: []Count{{"c", 2}, {"b", 1}, {"d", 1}, {"a", 0}},
+			LongestChain:           []string{"a", "b", "c"},
+			Leaves:                 []string{"c", "d"},
+			Roots:                  []string{"a"},
+		}},
+		{0, Stats{
+			Packages:               4,
+			Dependencies:           3,
+			MostDirectReverse:      []Count{},
+			MostTransitiveReverse:  []Count{},
+			LargestForwardClosures: []Count{},
+			LongestChain:           []string{"a", "b", "c"},
+			Leaves:                 []string{"c", "d"},
+			Roots:                  []string{"a"},
+		}},
+		{-1, Stats{
+			Packages:               4,
+			Dependencies:           3,
+			MostDirectReverse:      []Count{},
+			MostTransitiveReverse:  []Count{},
+			LargestForwardClosures: []Count{},
+			LongestChain:           []string{"a", "b", "c"},
+			Leaves:                 []string{"c", "d"},
+			Roots:                  []string{"a"},
+		}},
+	}
+	for _, test := range tests {
+		if stats := g.Stats(test.n); !reflect.DeepEqual(*stats, test.stats) {
+			t.Errorf("Stats(%d) = %+v, expected %+v", test.n, *stats, test.stats)
+		}
+	}
+	stats := empty().Stats(10)
+	expected := Stats{
+		MostDirectReverse:      []Count{},
+		MostTransitiveReverse:  []Count{},
+		LargestForwardClosures: []Count{},
+		LongestChain:           []string{},
+		Leaves:                 []string{},
+		Roots:                  []string{},
+	}
+	if !reflect.DeepEqual(*stats, expected) {
+		t.Errorf("Stats of empty = %+v, expected %+v", *stats, expected)
+	}
+}
@@ -0,0 +1,113 @@
+//
+// Copyright 2018-2021 Bryan T. Meyers <root@datadrake.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package index
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrPackageNotFound is wrapped by any error for a package missing from an Index
+var ErrPackageNotFound = errors.New("is not in the index")
+
+// closure follows edges out from the named packages, returning every package reached,
+// including the named ones
+func (i *Index) closure(edges map[string][]string, names []string) (map[string]bool, error) {
+	known := make(map[string]bool)
+	for _, pkg := range i.Packages {
+		known[pkg.Name] = true
+	}
+	reached := make(map[string]bool)
+	var queue []string
+	for _, name := range names {
+		if !known[name] {
+			return nil, fmt.Errorf("Package '%s' %w", name, ErrPackageNotFound)
+		}
+		if !reached[name] {
+			reached[name] = true
+			queue = append(queue, name)
+		}
+	}
+	for len(queue) > 0 {
+		name := queue[0]
+		queue = queue[1:]
+		for _, next := range edges[name] {
+			// Dependencies missing from the index cannot be brought along
+			if known[next] && !reached[next] {
+				reached[next] = true
+				queue = append(queue, next)
+			}
+		}
+	}
+	return reached, nil
+}
+
+// subset gets a new Index of the packages in keep, in the same order as this one
+func (i *Index) subset(keep map[string]bool) *Index {
+	sub := NewIndex()
+	for _, pkg := range i.Packages {
+		if keep[pkg.Name] {
+			sub.Packages = append(sub.Packages, pkg)
+		}
+	}
+	return sub
+}
+
+// forwardEdges maps every package to its runtime dependencies
+func (i *Index) forwardEdges() map[string][]string {
+	edges := make(map[string][]string)
+	for _, pkg := range i.Packages {
+		for _, dep := range pkg.RuntimeDependencies {
+			edges[pkg.Name] = append(edges[pkg.Name], dep.Name)
+		}
+	}
+	return edges
+}
+
+// Forward gets a new Index of the named packages and everything they depend on, directly or not
+func (i *Index) Forward(names ...string) (*Index, error) {
+	keep, err := i.closure(i.forwardEdges(), names)
+	if err != nil {
+		return nil, err
+	}
+	return i.subset(keep), nil
+}
+
+// Reverse gets a new Index of the named packages and everything which depends on them, directly
+// or not, along with whatever else those depend on to keep the Index self-contained
+func (i *Index) Reverse(names ...string) (*Index, error) {
+	forward := i.forwardEdges()
+	reverse := make(map[string][]string)
+	for name, deps := range forward {
+		for _, dep := range deps {
+			reverse[dep] = append(reverse[dep], name)
+		}
+	}
+	revs, err := i.closure(reverse, names)
+	if err != nil {
+		return nil, err
+	}
+	var roots []string
+	for name := range revs {
+		roots = append(roots, name)
+	}
+	keep, err := i.closure(forward, roots)
+	if err != nil {
+		return nil, err
+	}
+	return i.subset(keep), nil
+}
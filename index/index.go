@@ -17,7 +17,9 @@
 package index
 
 import (
+	"bufio"
 	"encoding/xml"
+	"io"
 	"os"
 	"text/template"
 )
@@ -43,12 +45,15 @@ func init() {
 
 // Index represents all of the packages in the eopkg index
 type Index struct {
+	XMLName  xml.Name  `xml:"PISI"`
 	Packages []Package `xml:"Package"`
 }
 
 // NewIndex returns an uninitialized Index
 func NewIndex() *Index {
-	return &Index{}
+	return &Index{
+		XMLName: xml.Name{Local: "PISI"},
+	}
 }
 
 // Load populates theis Index from an actual eopkg index
@@ -62,6 +67,37 @@ func (i *Index) Load(filepath string) error {
 	return d.Decode(&i)
 }
 
+// Write encodes this Index in the same layout as an actual eopkg index
+func (i *Index) Write(w io.Writer) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	e := xml.NewEncoder(w)
+	e.Indent("", "    ")
+	if err := e.Encode(i); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// Save writes this Index to a file, which Load can read back in
+func (i *Index) Save(filepath string) error {
+	f, err := os.Create(filepath)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(f)
+	if err = i.Write(w); err == nil {
+		err = w.Flush()
+	}
+	if err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
 // Graph prints out a graph representation of an index
 func (i *Index) Graph() error {
 	return outputTemplate.ExecuteTemplate(os.Stdout, "digraph", i)
@@ -0,0 +1,128 @@
+//
+// Copyright 2018-2021 Bryan T. Meyers <root@datadrake.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package index_test
+
+import (
+	"errors"
+	"github.com/DataDrake/eopkg-deps/index"
+	"github.com/DataDrake/eopkg-deps/index/indextest"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// fixture is a small index with a split -devel package, a -dbginfo package, an unknown
+// dependency and a cycle
+func fixture() *index.Index {
+	return indextest.New().
+		Package("zlib").Release(10, 9).Component("system.base").Summary("en", "Compression library").
+		Package("zlib-devel").Release(10).Component("system.devel").DepFrom("zlib", 10).PkgConfig("zlib").
+		Package("openssl").Release(5).Component("system.base").DepFrom("zlib", 9).PkgConfig("libssl").PkgConfig32("libssl").
+		Package("curl").Release(7).Component("network.util").Deps("openssl").DepFrom("zlib", 10).Deps("not-in-index").
+		Package("git").Release(3).Component("programming.tools").Deps("curl", "openssl").
+		Package("git-dbginfo").Release(3).Component("programming.tools").Deps("git").
+		Package("loop-a").Component("desktop").Deps("loop-b").
+		Package("loop-b").Component("desktop").Deps("loop-a").
+		Index()
+}
+
+// names lists the names of the packages in an Index, keeping their order
+func names(i *index.Index) []string {
+	found := make([]string, 0, len(i.Packages))
+	for _, pkg := range i.Packages {
+		found = append(found, pkg.Name)
+	}
+	return found
+}
+
+func TestSaveLoad(t *testing.T) {
+	tests := []struct {
+		what string
+		i    *index.Index
+	}{
+		{"empty", index.NewIndex()},
+		{"fixture", fixture()},
+		{"generated", generated()},
+	}
+	for _, test := range tests {
+		path := filepath.Join(t.TempDir(), "eopkg-index.xml")
+		if err := test.i.Save(path); err != nil {
+			t.Errorf("Save of %s: unexpected error: %s", test.what, err)
+			continue
+		}
+		loaded := index.NewIndex()
+		if err := loaded.Load(path); err != nil {
+			t.Errorf("Load of %s: unexpected error: %s", test.what, err)
+			continue
+		}
+		// Decoding leaves nil where there was nothing, which is what an empty Index starts with
+		if len(test.i.Packages) == 0 {
+			loaded.Packages = test.i.Packages
+		}
+		if !reflect.DeepEqual(loaded, test.i) {
+			t.Errorf("Load of %s: index read back differs from the one saved", test.what)
+		}
+	}
+}
+
+func TestExtract(t *testing.T) {
+	tests := []struct {
+		reverse  bool
+		names    []string
+		expected []string
+	}{
+		{false, []string{"zlib"}, []string{"zlib"}},
+		{false, []string{"curl"}, []string{"zlib", "openssl", "curl"}},
+		{false, []string{"curl", "openssl", "curl"}, []string{"zlib", "openssl", "curl"}},
+		{false, []string{"zlib-devel", "loop-a"}, []string{"zlib", "zlib-devel", "loop-a", "loop-b"}},
+		{false, []string{"git-dbginfo"}, []string{"zlib", "openssl", "curl", "git", "git-dbginfo"}},
+		{true, []string{"git-dbginfo"}, []string{"zlib", "openssl", "curl", "git", "git-dbginfo"}},
+		{true, []string{"git"}, []string{"zlib", "openssl", "curl", "git", "git-dbginfo"}},
+		// Everything which depends on openssl comes along with whatever else it depends on
+		{true, []string{"openssl"}, []string{"zlib", "openssl", "curl", "git", "git-dbginfo"}},
+		{true, []string{"loop-b"}, []string{"loop-a", "loop-b"}},
+		{true, []string{"zlib"}, []string{"zlib", "zlib-devel", "openssl", "curl", "git", "git-dbginfo"}},
+	}
+	for _, test := range tests {
+		extract := fixture().Forward
+		if test.reverse {
+			extract = fixture().Reverse
+		}
+		i, err := extract(test.names...)
+		if err != nil {
+			t.Errorf("Extract of %q (reverse %t): unexpected error: %s", test.names, test.reverse, err)
+			continue
+		}
+		if found := names(i); !reflect.DeepEqual(found, test.expected) {
+			t.Errorf("Extract of %q (reverse %t) = %q, expected %q", test.names, test.reverse, found, test.expected)
+		}
+	}
+	// Packages come across whole, dependencies on packages left out included
+	i, err := fixture().Forward("curl")
+	if err != nil {
+		t.Fatalf("Forward: unexpected error: %s", err)
+	}
+	if expected := fixture().Packages[3]; !reflect.DeepEqual(i.Packages[2], expected) {
+		t.Errorf("Forward: expected %+v, found %+v", expected, i.Packages[2])
+	}
+	if _, err = fixture().Forward("not-in-index"); !errors.Is(err, index.ErrPackageNotFound) {
+		t.Errorf("Forward: expected ErrPackageNotFound, found %v", err)
+	}
+	if _, err = fixture().Reverse("git-dbginfo", "not-in-index"); !errors.Is(err, index.ErrPackageNotFound) {
+		t.Errorf("Reverse: expected ErrPackageNotFound, found %v", err)
+	}
+}
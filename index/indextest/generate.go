@@ -0,0 +1,78 @@
+//
+// Copyright 2018-2021 Bryan T. Meyers <root@datadrake.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package indextest
+
+import (
+	"fmt"
+	"github.com/DataDrake/eopkg-deps/index"
+	"math/rand"
+)
+
+// Components is the number of components packages are spread over by the generators
+const Components = 20
+
+// Name gets the name of the nth package made by the generators
+func Name(n int) string {
+	return fmt.Sprintf("pkg%05d", n)
+}
+
+// RandomDAG generates size packages with no cycles, where each depends on a few packages
+// listed before it, favouring the first ones like the core libraries of a real repository.
+// The same seed always gives the same packages.
+func RandomDAG(size int, seed int64) *Builder {
+	r := rand.New(rand.NewSource(seed))
+	b := New()
+	for n := 0; n < size; n++ {
+		b.Package(Name(n)).Component(fmt.Sprintf("component.%d", n%Components)).Release(1 + r.Intn(50))
+		for i := r.Intn(6); i > 0 && n > 0; i-- {
+			// Squaring skews the choice towards the start of the index
+			f := r.Float64()
+			b.Deps(Name(int(f * f * float64(n))))
+		}
+	}
+	return b
+}
+
+// RandomCyclic generates the same packages as RandomDAG, then closes up to cycles loops by
+// following the dependencies of a random package a few levels down and depending back on it
+func RandomCyclic(size, cycles int, seed int64) *Builder {
+	b := RandomDAG(size, seed)
+	pkgs := b.index.Packages
+	positions := make(map[string]int)
+	for n, pkg := range pkgs {
+		positions[pkg.Name] = n
+	}
+	r := rand.New(rand.NewSource(seed + 1))
+	for c := 0; c < cycles && size > 1; c++ {
+		top := 1 + r.Intn(size-1)
+		bottom := top
+		for steps := 1 + r.Intn(3); steps > 0; steps-- {
+			deps := pkgs[bottom].RuntimeDependencies
+			if len(deps) == 0 {
+				break
+			}
+			bottom = positions[deps[r.Intn(len(deps))].Name]
+		}
+		if bottom == top {
+			continue
+		}
+		pkgs[bottom].RuntimeDependencies = append(pkgs[bottom].RuntimeDependencies, index.Dependency{
+			Name: pkgs[top].Name,
+		})
+	}
+	return b
+}
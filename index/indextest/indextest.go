@@ -0,0 +1,130 @@
+//
+// Copyright 2018-2021 Bryan T. Meyers <root@datadrake.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+// Package indextest builds eopkg indices for tests and benchmarks, without hand-writing XML:
+//
+//	i := indextest.New().
+//	    Package("zlib").Release(3, 2, 1).Component("system.base").
+//	    Package("curl").Deps("zlib").Summary("en", "Transfer data with URLs").
+//	    Index()
+//
+// Indices can also be generated at random from a seed, and saved with index.Index.Save
+// to go through the same parser as the repository index.
+package indextest
+
+import (
+	"github.com/DataDrake/eopkg-deps/index"
+)
+
+// Builder adds packages to an index one at a time, where every other method changes the
+// package most recently started by Package
+type Builder struct {
+	index *index.Index
+}
+
+// New gets a Builder for an empty index
+func New() *Builder {
+	return &Builder{
+		index: index.NewIndex(),
+	}
+}
+
+// current gets the package being built
+func (b *Builder) current() *index.Package {
+	if len(b.index.Packages) == 0 {
+		panic("indextest: Package must be called first")
+	}
+	return &b.index.Packages[len(b.index.Packages)-1]
+}
+
+// Package starts a new package at release 1, built from a source of the same name
+func (b *Builder) Package(name string) *Builder {
+	b.index.Packages = append(b.index.Packages, index.Package{
+		Name:     name,
+		Source:   name,
+		Releases: []index.Update{{Number: 1}},
+	})
+	return b
+}
+
+// Release replaces the history of the package, newest release first
+func (b *Builder) Release(numbers ...int) *Builder {
+	pkg := b.current()
+	pkg.Releases = nil
+	for _, number := range numbers {
+		pkg.Releases = append(pkg.Releases, index.Update{Number: number})
+	}
+	return b
+}
+
+// Deps adds runtime dependencies on any release of each package
+func (b *Builder) Deps(names ...string) *Builder {
+	for _, name := range names {
+		b.DepFrom(name, 0)
+	}
+	return b
+}
+
+// DepFrom adds a runtime dependency on a package from a minimum release
+func (b *Builder) DepFrom(name string, release int) *Builder {
+	pkg := b.current()
+	pkg.RuntimeDependencies = append(pkg.RuntimeDependencies, index.Dependency{
+		Name:    name,
+		Release: release,
+	})
+	return b
+}
+
+// Source sets the name of the source the package is built from
+func (b *Builder) Source(name string) *Builder {
+	b.current().Source = name
+	return b
+}
+
+// Component sets the component the package is part of
+func (b *Builder) Component(name string) *Builder {
+	b.current().Component = name
+	return b
+}
+
+// Summary adds a summary of the package in a language
+func (b *Builder) Summary(lang, text string) *Builder {
+	pkg := b.current()
+	pkg.Summaries = append(pkg.Summaries, index.Summary{
+		Lang: lang,
+		Text: text,
+	})
+	return b
+}
+
+// PkgConfig adds pkg-config names provided by the package
+func (b *Builder) PkgConfig(names ...string) *Builder {
+	pkg := b.current()
+	pkg.Provides.PkgConfig = append(pkg.Provides.PkgConfig, names...)
+	return b
+}
+
+// PkgConfig32 adds 32-bit pkg-config names provided by the package
+func (b *Builder) PkgConfig32(names ...string) *Builder {
+	pkg := b.current()
+	pkg.Provides.PkgConfig32 = append(pkg.Provides.PkgConfig32, names...)
+	return b
+}
+
+// Index gets the index built so far, which is shared with the Builder
+func (b *Builder) Index() *index.Index {
+	return b.index
+}
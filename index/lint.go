@@ -0,0 +1,184 @@
+//
+// Copyright 2018-2021 Bryan T. Meyers <root@datadrake.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package index
+
+import (
+	"fmt"
+)
+
+// Severity is how much a Finding matters
+type Severity int
+
+// Severities of findings, from least to most serious
+const (
+	// Warning is for something suspicious which can still be imported as is
+	Warning Severity = iota
+	// Error is for a package which has to be left out when importing
+	Error
+)
+
+// String gets the name of a Severity
+func (s Severity) String() string {
+	if s == Error {
+		return "error"
+	}
+	return "warning"
+}
+
+// Finding is a single problem with a package in an Index
+type Finding struct {
+	Severity Severity
+	Package  string
+	Message  string
+}
+
+// String gets a one-line description of a Finding
+func (f Finding) String() string {
+	return fmt.Sprintf("%s: Package '%s' %s", f.Severity, f.Package, f.Message)
+}
+
+// Findings are all of the problems with an Index
+type Findings []Finding
+
+// Count gets how many findings are of a Severity
+func (fs Findings) Count(severity Severity) int {
+	count := 0
+	for _, f := range fs {
+		if f.Severity == severity {
+			count++
+		}
+	}
+	return count
+}
+
+// Lint checks the Index for anything which cannot be imported as is (see Linter.Findings)
+func (i *Index) Lint() Findings {
+	// Walking an Index never fails
+	findings, _ := LintWalk(i)
+	return findings
+}
+
+// linted is as much of a package as Lint needs to keep around
+type linted struct {
+	name    string
+	count   int
+	release int
+	history bool
+	deps    []Dependency
+}
+
+// Linter collects as much of each package as Lint needs while they go by, so that the checks can
+// share a walk with something else, like an import
+type Linter struct {
+	pkgs  []*linted
+	first map[string]*linted
+}
+
+// NewLinter gets a Linter which has not seen any packages yet
+func NewLinter() *Linter {
+	return &Linter{
+		first: make(map[string]*linted),
+	}
+}
+
+// Add collects a package, which must be seen in the order of the index and only once per walk
+func (l *Linter) Add(pkg *Package) {
+	// Only the first of any duplicates counts, like when importing
+	if prev, ok := l.first[pkg.Name]; ok {
+		prev.count++
+		return
+	}
+	p := &linted{
+		name:    pkg.Name,
+		count:   1,
+		history: len(pkg.Releases) > 0,
+		deps:    pkg.RuntimeDependencies,
+	}
+	if p.history {
+		p.release = pkg.Releases[0].Number
+	}
+	l.pkgs = append(l.pkgs, p)
+	l.first[pkg.Name] = p
+}
+
+// lintingWalker passes every package to a Linter on its way through a walk
+type lintingWalker struct {
+	linter *Linter
+	w      Walker
+}
+
+// Walk goes through the packages of the wrapped Walker, adding each to the Linter first
+func (lw lintingWalker) Walk(fn func(pkg *Package) error) error {
+	return lw.w.Walk(func(pkg *Package) error {
+		lw.linter.Add(pkg)
+		return fn(pkg)
+	})
+}
+
+// Through gets a Walker which adds every package to this Linter as w is walked, which must only happen once
+func (l *Linter) Through(w Walker) Walker {
+	return lintingWalker{l, w}
+}
+
+// Findings checks every package added so far, in the order they were listed:
+//
+//   - duplicate names and packages without a release history are errors
+//   - self-dependencies, dependencies on unknown packages and a releaseFrom above the current
+//     release of the dependency are warnings
+func (l *Linter) Findings() Findings {
+	var findings Findings
+	add := func(severity Severity, name, format string, args ...interface{}) {
+		findings = append(findings, Finding{severity, name, fmt.Sprintf(format, args...)})
+	}
+	for _, pkg := range l.pkgs {
+		if pkg.count > 1 {
+			add(Error, pkg.name, "is listed %d times, only the first is used", pkg.count)
+		}
+		if !pkg.history {
+			add(Error, pkg.name, "has no release history")
+			continue
+		}
+		for _, dep := range pkg.deps {
+			target, ok := l.first[dep.Name]
+			switch {
+			case dep.Name == pkg.name:
+				add(Warning, pkg.name, "depends on itself")
+			case !ok:
+				add(Warning, pkg.name, "depends on unknown package '%s'", dep.Name)
+			case !target.history:
+				// Already an error for the dependency
+			case dep.Release > target.release:
+				add(Warning, pkg.name, "needs release %d of '%s', which is only at release %d",
+					dep.Release, dep.Name, target.release)
+			}
+		}
+	}
+	return findings
+}
+
+// LintWalk checks an index for anything which cannot be imported as is (see Linter.Findings)
+func LintWalk(w Walker) (Findings, error) {
+	l := NewLinter()
+	err := w.Walk(func(pkg *Package) error {
+		l.Add(pkg)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return l.Findings(), nil
+}
@@ -0,0 +1,99 @@
+//
+// Copyright 2018-2021 Bryan T. Meyers <root@datadrake.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package index_test
+
+import (
+	"github.com/DataDrake/eopkg-deps/index"
+	"github.com/DataDrake/eopkg-deps/index/indextest"
+	"reflect"
+	"testing"
+)
+
+func TestLint(t *testing.T) {
+	tests := []struct {
+		what     string
+		i        *index.Index
+		findings index.Findings
+	}{
+		{"empty", index.NewIndex(), nil},
+		{"clean", indextest.RandomDAG(50, 1).Index(), nil},
+		{"duplicate", indextest.New().Package("zlib").Package("zlib").Package("zlib").Index(), index.Findings{
+			{Severity: index.Error, Package: "zlib", Message: "is listed 3 times, only the first is used"},
+		}},
+		{"no history", indextest.New().Package("zlib").Release().Index(), index.Findings{
+			{Severity: index.Error, Package: "zlib", Message: "has no release history"},
+		}},
+		// Nothing else is checked for a package which cannot be imported
+		{"no history with bad deps", indextest.New().Package("zlib").Release().Deps("zlib", "missing").Index(), index.Findings{
+			{Severity: index.Error, Package: "zlib", Message: "has no release history"},
+		}},
+		{"self-dependency", indextest.New().Package("zlib").Deps("zlib").Index(), index.Findings{
+			{Severity: index.Warning, Package: "zlib", Message: "depends on itself"},
+		}},
+		{"unknown dependency", indextest.New().Package("curl").Deps("zlib").Index(), index.Findings{
+			{Severity: index.Warning, Package: "curl", Message: "depends on unknown package 'zlib'"},
+		}},
+		{"future release", indextest.New().Package("zlib").Release(3).Package("curl").DepFrom("zlib", 4).Index(), index.Findings{
+			{Severity: index.Warning, Package: "curl", Message: "needs release 4 of 'zlib', which is only at release 3"},
+		}},
+		{"current release", indextest.New().Package("zlib").Release(3).Package("curl").DepFrom("zlib", 3).Index(), nil},
+		// A dependency listed later in the index is still known
+		{"later dependency", indextest.New().Package("curl").DepFrom("zlib", 2).Package("zlib").Release(2).Index(), nil},
+		// The dependency is already an error, so its release is not compared
+		{"dependency without history", indextest.New().Package("zlib").Release().Package("curl").DepFrom("zlib", 4).Index(), index.Findings{
+			{Severity: index.Error, Package: "zlib", Message: "has no release history"},
+		}},
+		// Only the first of duplicates is used, so its release is the one compared
+		{"dependency on a duplicate", indextest.New().
+			Package("zlib").Release(3).
+			Package("curl").DepFrom("zlib", 4).
+			Package("zlib").Release(4).
+			Index(), index.Findings{
+			{Severity: index.Error, Package: "zlib", Message: "is listed 2 times, only the first is used"},
+			{Severity: index.Warning, Package: "curl", Message: "needs release 4 of 'zlib', which is only at release 3"},
+		}},
+	}
+	for _, test := range tests {
+		if findings := test.i.Lint(); !reflect.DeepEqual(findings, test.findings) {
+			t.Errorf("Lint of %s = %v, expected %v", test.what, findings, test.findings)
+		}
+	}
+}
+
+func TestFindings(t *testing.T) {
+	findings := index.Findings{
+		{Severity: index.Error, Package: "zlib", Message: "has no release history"},
+		{Severity: index.Warning, Package: "curl", Message: "depends on itself"},
+		{Severity: index.Warning, Package: "git", Message: "depends on unknown package 'x'"},
+	}
+	if count := findings.Count(index.Error); count != 1 {
+		t.Errorf("Count of errors = %d, expected 1", count)
+	}
+	if count := findings.Count(index.Warning); count != 2 {
+		t.Errorf("Count of warnings = %d, expected 2", count)
+	}
+	expected := []string{
+		"error: Package 'zlib' has no release history",
+		"warning: Package 'curl' depends on itself",
+		"warning: Package 'git' depends on unknown package 'x'",
+	}
+	for n, finding := range findings {
+		if s := finding.String(); s != expected[n] {
+			t.Errorf("String = %q, expected %q", s, expected[n])
+		}
+	}
+}
@@ -0,0 +1,59 @@
+//
+// Copyright 2018-2021 Bryan T. Meyers <root@datadrake.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package index
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"fmt"
+)
+
+// MetadataFile is the name of the package metadata inside of a .eopkg archive
+const MetadataFile = "metadata.xml"
+
+// Metadata represents the contents of the metadata.xml in a .eopkg archive
+type Metadata struct {
+	Package Package `xml:"Package"`
+}
+
+// LoadMetadata reads the Package metadata from a built .eopkg archive
+func LoadMetadata(filepath string) (*Package, error) {
+	r, err := zip.OpenReader(filepath)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	for _, f := range r.File {
+		if f.Name != MetadataFile {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return nil, err
+		}
+		defer rc.Close()
+		var m Metadata
+		if err = xml.NewDecoder(rc).Decode(&m); err != nil {
+			return nil, err
+		}
+		if len(m.Package.Releases) == 0 {
+			return nil, fmt.Errorf("'%s' has no release history", filepath)
+		}
+		return &m.Package, nil
+	}
+	return nil, fmt.Errorf("'%s' does not contain a %s", filepath, MetadataFile)
+}
@@ -16,14 +16,93 @@
 
 package index
 
+import (
+	"encoding/xml"
+)
+
 // Package represents a single package and its immediate dependencies
 type Package struct {
-	Name     string `xml:"Name"`
-	Releases []struct {
-		Number int `xml:"release,attr"`
-	} `xml:"History>Update"`
-	RuntimeDependencies []struct {
-		Name    string `xml:",chardata"`
-		Release int    `xml:"releaseFrom,attr"`
-	} `xml:"RuntimeDependencies>Dependency"`
+	Name                string       `xml:"Name"`
+	Summaries           []Summary    `xml:"Summary"`
+	Component           string       `xml:"PartOf"`
+	Source              string       `xml:"Source>Name"`
+	RuntimeDependencies []Dependency `xml:"RuntimeDependencies>Dependency"`
+	Releases            []Update     `xml:"History>Update"`
+	Provides            Provides     `xml:"Provides"`
+}
+
+// Summary is a short description of a package in a single language
+type Summary struct {
+	Lang string `xml:"http://www.w3.org/XML/1998/namespace lang,attr,omitempty"`
+	Text string `xml:",chardata"`
+}
+
+// Update is a single entry in the history of a package, newest first
+type Update struct {
+	Number int `xml:"release,attr"`
+}
+
+// Dependency is a package needed at runtime, from a minimum release (unless zero)
+type Dependency struct {
+	Name    string `xml:",chardata"`
+	Release int    `xml:"releaseFrom,attr,omitempty"`
+}
+
+// Provides lists the pkg-config names a package can satisfy
+type Provides struct {
+	PkgConfig   []string `xml:"PkgConfig"`
+	PkgConfig32 []string `xml:"PkgConfig32"`
+}
+
+// MarshalXML writes a Package like eopkg does, leaving out empty sections which encoding/xml
+// would otherwise write for every nested field
+func (p Package) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	type source struct {
+		Name string `xml:"Name"`
+	}
+	type dependencies struct {
+		Dependencies []Dependency `xml:"Dependency"`
+	}
+	type history struct {
+		Updates []Update `xml:"Update"`
+	}
+	out := struct {
+		Name                string        `xml:"Name"`
+		Summaries           []Summary     `xml:"Summary"`
+		Component           string        `xml:"PartOf,omitempty"`
+		Source              *source       `xml:"Source"`
+		RuntimeDependencies *dependencies `xml:"RuntimeDependencies"`
+		Releases            *history      `xml:"History"`
+		Provides            *Provides     `xml:"Provides"`
+	}{
+		Name:      p.Name,
+		Summaries: p.Summaries,
+		Component: p.Component,
+	}
+	if len(p.Source) > 0 {
+		out.Source = &source{p.Source}
+	}
+	if len(p.RuntimeDependencies) > 0 {
+		out.RuntimeDependencies = &dependencies{p.RuntimeDependencies}
+	}
+	if len(p.Releases) > 0 {
+		out.Releases = &history{p.Releases}
+	}
+	if len(p.Provides.PkgConfig) > 0 || len(p.Provides.PkgConfig32) > 0 {
+		out.Provides = &p.Provides
+	}
+	return e.EncodeElement(out, start)
+}
+
+// Summary gets the English summary of a package, falling back to the first one listed
+func (p *Package) Summary() string {
+	for _, summary := range p.Summaries {
+		if summary.Lang == "en" {
+			return summary.Text
+		}
+	}
+	if len(p.Summaries) > 0 {
+		return p.Summaries[0].Text
+	}
+	return ""
 }
@@ -0,0 +1,118 @@
+//
+// Copyright 2018-2021 Bryan T. Meyers <root@datadrake.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package index
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"os"
+	"time"
+)
+
+// Stamp identifies the contents of an index file at one point in time
+type Stamp struct {
+	Path    string
+	ModTime time.Time
+	Hash    string
+}
+
+// hash gets the SHA-256 of everything in the file
+func (f File) hash() (string, error) {
+	r, err := os.Open(string(f))
+	if err != nil {
+		return "", err
+	}
+	defer r.Close()
+	h := sha256.New()
+	if _, err = io.Copy(h, r); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// Stamp gets a Stamp for what is currently in this File
+func (f File) Stamp() (Stamp, error) {
+	info, err := os.Stat(string(f))
+	if err != nil {
+		return Stamp{}, err
+	}
+	hash, err := f.hash()
+	if err != nil {
+		return Stamp{}, err
+	}
+	return Stamp{
+		Path:    string(f),
+		ModTime: info.ModTime(),
+		Hash:    hash,
+	}, nil
+}
+
+// Changed checks if this File is different from when it was stamped, only reading the
+// whole file when the modification time has moved
+func (f File) Changed(since Stamp) (bool, error) {
+	if string(f) != since.Path {
+		return true, nil
+	}
+	info, err := os.Stat(string(f))
+	if err != nil {
+		return false, err
+	}
+	if info.ModTime().Equal(since.ModTime) {
+		return false, nil
+	}
+	hash, err := f.hash()
+	if err != nil {
+		return false, err
+	}
+	return hash != since.Hash, nil
+}
+
+// Stamper is a File which works out its Stamp while being walked, so that importing it and
+// stamping it only take one read
+type Stamper struct {
+	File  File
+	Stamp Stamp
+}
+
+// Walk goes through the packages of the File in order, setting the Stamp once all of it has been read
+func (s *Stamper) Walk(fn func(pkg *Package) error) error {
+	r, err := os.Open(string(s.File))
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	// The open file is the one being read, even if the path gets replaced part way through
+	info, err := r.Stat()
+	if err != nil {
+		return err
+	}
+	h := sha256.New()
+	if err = Stream(io.TeeReader(r, h), fn); err != nil {
+		return err
+	}
+	// Anything the decoder did not need still counts towards the hash
+	if _, err = io.Copy(h, r); err != nil {
+		return err
+	}
+	s.Stamp = Stamp{
+		Path:    string(s.File),
+		ModTime: info.ModTime(),
+		Hash:    hex.EncodeToString(h.Sum(nil)),
+	}
+	return nil
+}
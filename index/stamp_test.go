@@ -0,0 +1,105 @@
+//
+// Copyright 2018-2021 Bryan T. Meyers <root@datadrake.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package index_test
+
+import (
+	"github.com/DataDrake/eopkg-deps/index"
+	"github.com/DataDrake/eopkg-deps/index/indextest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// sameStamp checks if two stamps are for the same contents, whatever time zone they were read in
+func sameStamp(a, b index.Stamp) bool {
+	return a.Path == b.Path && a.ModTime.Equal(b.ModTime) && a.Hash == b.Hash
+}
+
+func TestStamp(t *testing.T) {
+	dir := t.TempDir()
+	f := index.File(filepath.Join(dir, "eopkg-index.xml"))
+	if err := fixture().Save(string(f)); err != nil {
+		t.Fatalf("Save: unexpected error: %s", err)
+	}
+	stamp, err := f.Stamp()
+	if err != nil {
+		t.Fatalf("Stamp: unexpected error: %s", err)
+	}
+	if stamp.Path != string(f) || stamp.ModTime.IsZero() || len(stamp.Hash) != 64 {
+		t.Errorf("Stamp = %+v, expected the path, a time and a SHA-256", stamp)
+	}
+	// Walking and stamping in one read gives the same as stamping separately
+	stamper := &index.Stamper{File: f}
+	if _, err = walked(stamper); err != nil {
+		t.Fatalf("Walk of Stamper: unexpected error: %s", err)
+	}
+	if !sameStamp(stamper.Stamp, stamp) {
+		t.Errorf("Stamper = %+v, expected %+v", stamper.Stamp, stamp)
+	}
+	if _, err = index.File(filepath.Join(dir, "missing.xml")).Stamp(); err == nil {
+		t.Errorf("Stamp of a missing File: expected an error")
+	}
+}
+
+// expectChanged checks if a File is different from a Stamp
+func expectChanged(t *testing.T, what string, f index.File, since index.Stamp, expected bool) {
+	t.Helper()
+	changed, err := f.Changed(since)
+	if err != nil {
+		t.Errorf("Changed when %s: unexpected error: %s", what, err)
+		return
+	}
+	if changed != expected {
+		t.Errorf("Changed when %s = %t, expected %t", what, changed, expected)
+	}
+}
+
+func TestChanged(t *testing.T) {
+	dir := t.TempDir()
+	f := index.File(filepath.Join(dir, "eopkg-index.xml"))
+	if err := fixture().Save(string(f)); err != nil {
+		t.Fatalf("Save: unexpected error: %s", err)
+	}
+	stamp, err := f.Stamp()
+	if err != nil {
+		t.Fatalf("Stamp: unexpected error: %s", err)
+	}
+	expectChanged(t, "unchanged", f, stamp, false)
+	expectChanged(t, "nothing was stamped", f, index.Stamp{}, true)
+	expectChanged(t, "at a different path", index.File(string(f)+".old"), stamp, true)
+	// Touching the file alone does not count as a change
+	later := stamp.ModTime.Add(time.Minute)
+	if err = os.Chtimes(string(f), later, later); err != nil {
+		t.Fatalf("Chtimes: unexpected error: %s", err)
+	}
+	expectChanged(t, "touched", f, stamp, false)
+	if err = indextest.New().Package("zlib").Index().Save(string(f)); err != nil {
+		t.Fatalf("Save: unexpected error: %s", err)
+	}
+	// Filesystems with coarse timestamps could otherwise leave the time as it was stamped
+	if err = os.Chtimes(string(f), later, later); err != nil {
+		t.Fatalf("Chtimes: unexpected error: %s", err)
+	}
+	expectChanged(t, "rewritten", f, stamp, true)
+	if err = os.Remove(string(f)); err != nil {
+		t.Fatalf("Remove: unexpected error: %s", err)
+	}
+	if _, err = f.Changed(stamp); err == nil {
+		t.Errorf("Changed when removed: expected an error")
+	}
+}
@@ -0,0 +1,83 @@
+//
+// Copyright 2018-2021 Bryan T. Meyers <root@datadrake.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package index
+
+import (
+	"encoding/xml"
+	"io"
+	"os"
+)
+
+// Walker goes through the packages of an index one at a time, stopping at the first error from fn
+type Walker interface {
+	Walk(fn func(pkg *Package) error) error
+}
+
+// Walk goes through the packages of this Index in order
+func (i *Index) Walk(fn func(pkg *Package) error) error {
+	for n := range i.Packages {
+		if err := fn(&i.Packages[n]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Stream decodes the packages of an eopkg index one at a time, so only one is ever held in memory
+func Stream(r io.Reader, fn func(pkg *Package) error) error {
+	d := xml.NewDecoder(r)
+	depth := 0
+	for {
+		tok, err := d.Token()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			if depth != 1 || t.Name.Local != "Package" {
+				depth++
+				continue
+			}
+			// Decoding appends to slices, so every package needs a fresh value
+			var pkg Package
+			if err = d.DecodeElement(&pkg, &t); err != nil {
+				return err
+			}
+			if err = fn(&pkg); err != nil {
+				return err
+			}
+		case xml.EndElement:
+			depth--
+		}
+	}
+}
+
+// File is an eopkg index on disk, streamed every time it is walked instead of being loaded in full
+type File string
+
+// Walk goes through the packages of this File in order
+func (f File) Walk(fn func(pkg *Package) error) error {
+	r, err := os.Open(string(f))
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	return Stream(r, fn)
+}
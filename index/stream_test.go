@@ -0,0 +1,172 @@
+//
+// Copyright 2018-2021 Bryan T. Meyers <root@datadrake.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package index_test
+
+import (
+	"errors"
+	"github.com/DataDrake/eopkg-deps/index"
+	"github.com/DataDrake/eopkg-deps/index/indextest"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// generated is a larger index with duplicates and characters which need escaping
+func generated() *index.Index {
+	return indextest.RandomCyclic(200, 10, 1).
+		Package("dep-&-escapes").Summary("en", "Needs <escaping> & \"quotes\"").Summary("de", "Ä").PkgConfig("escapes").
+		Package("dep-user").Source("dep").DepFrom("dep-&-escapes", 2).Deps("not-in-index").
+		Package("dep-user").Release().Deps("dep-user").
+		Index()
+}
+
+// walked collects every package a Walker goes through
+func walked(w index.Walker) ([]index.Package, error) {
+	var pkgs []index.Package
+	err := w.Walk(func(pkg *index.Package) error {
+		pkgs = append(pkgs, *pkg)
+		return nil
+	})
+	return pkgs, err
+}
+
+func TestWalk(t *testing.T) {
+	expected := generated()
+	path := filepath.Join(t.TempDir(), "eopkg-index.xml")
+	if err := expected.Save(path); err != nil {
+		t.Fatalf("Save: unexpected error: %s", err)
+	}
+	loaded := index.NewIndex()
+	if err := loaded.Load(path); err != nil {
+		t.Fatalf("Load: unexpected error: %s", err)
+	}
+	walkers := []struct {
+		what string
+		w    index.Walker
+	}{
+		{"Index", expected},
+		{"File", index.File(path)},
+		{"Stamper", &index.Stamper{File: index.File(path)}},
+	}
+	for _, walker := range walkers {
+		pkgs, err := walked(walker.w)
+		if err != nil {
+			t.Errorf("Walk of %s: unexpected error: %s", walker.what, err)
+			continue
+		}
+		if !reflect.DeepEqual(pkgs, loaded.Packages) {
+			t.Errorf("Walk of %s: packages differ from those loaded", walker.what)
+		}
+	}
+}
+
+func TestStream(t *testing.T) {
+	tests := []struct {
+		xml      string
+		expected []string
+		fail     bool
+	}{
+		{"", nil, false},
+		{"<PISI></PISI>", nil, false},
+		{"<PISI><Package><Name>zlib</Name></Package><Package><Name>curl</Name></Package></PISI>", []string{"zlib", "curl"}, false},
+		// Only packages directly inside the root count
+		{"<PISI><Distribution><Package><Name>zlib</Name></Package></Distribution></PISI>", nil, false},
+		{"<PISI><Package><Name>zlib</Name></Package><Package>", []string{"zlib"}, true},
+		{"<PISI><Package><Name>zlib</Name></Pkg></PISI>", nil, true},
+	}
+	for _, test := range tests {
+		var found []string
+		err := index.Stream(strings.NewReader(test.xml), func(pkg *index.Package) error {
+			found = append(found, pkg.Name)
+			return nil
+		})
+		if (err != nil) != test.fail {
+			t.Errorf("Stream(%q): expected failure %t, found error %v", test.xml, test.fail, err)
+		}
+		if !reflect.DeepEqual(found, test.expected) {
+			t.Errorf("Stream(%q) = %q, expected %q", test.xml, found, test.expected)
+		}
+	}
+}
+
+func TestWalkStops(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "eopkg-index.xml")
+	if err := fixture().Save(path); err != nil {
+		t.Fatalf("Save: unexpected error: %s", err)
+	}
+	stop := errors.New("stop")
+	walkers := []struct {
+		what string
+		w    index.Walker
+	}{
+		{"Index", fixture()},
+		{"File", index.File(path)},
+		{"Stamper", &index.Stamper{File: index.File(path)}},
+	}
+	for _, walker := range walkers {
+		count := 0
+		err := walker.w.Walk(func(pkg *index.Package) error {
+			count++
+			if pkg.Name == "openssl" {
+				return stop
+			}
+			return nil
+		})
+		if err != stop {
+			t.Errorf("Walk of %s: expected %v, found %v", walker.what, stop, err)
+		}
+		if count != 3 {
+			t.Errorf("Walk of %s: expected to stop after 3 packages, found %d", walker.what, count)
+		}
+	}
+	missing := index.File(filepath.Join(t.TempDir(), "missing.xml"))
+	if _, err := walked(missing); err == nil {
+		t.Errorf("Walk of a missing File: expected an error")
+	}
+}
+
+func TestLintWalk(t *testing.T) {
+	i := generated()
+	path := filepath.Join(t.TempDir(), "eopkg-index.xml")
+	if err := i.Save(path); err != nil {
+		t.Fatalf("Save: unexpected error: %s", err)
+	}
+	expected := i.Lint()
+	if len(expected) == 0 {
+		t.Fatalf("Lint: expected findings for the generated index")
+	}
+	findings, err := index.LintWalk(index.File(path))
+	if err != nil {
+		t.Fatalf("LintWalk: unexpected error: %s", err)
+	}
+	if !reflect.DeepEqual(findings, expected) {
+		t.Errorf("LintWalk = %v, expected %v", findings, expected)
+	}
+	// Linting along the way of another walk finds the same and still passes every package on
+	l := index.NewLinter()
+	pkgs, err := walked(l.Through(index.File(path)))
+	if err != nil {
+		t.Fatalf("Through: unexpected error: %s", err)
+	}
+	if len(pkgs) != len(i.Packages) {
+		t.Errorf("Through: expected %d packages, found %d", len(i.Packages), len(pkgs))
+	}
+	if findings = l.Findings(); !reflect.DeepEqual(findings, expected) {
+		t.Errorf("Findings = %v, expected %v", findings, expected)
+	}
+}
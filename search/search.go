@@ -0,0 +1,245 @@
+//
+// Copyright 2018-2021 Bryan T. Meyers <root@datadrake.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package search
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Matcher checks if a string matches a pattern
+type Matcher func(s string) bool
+
+// ErrBadGlob means a glob has an unclosed or empty character class
+var ErrBadGlob = errors.New("syntax error in glob")
+
+// Glob matches shell-style patterns, or substrings when the pattern has no wildcards, ignoring case.
+// Unlike path.Match, '*' and '?' match any character, including '/'.
+func Glob(pattern string) (Matcher, error) {
+	if !strings.ContainsAny(pattern, "*?[") {
+		pattern = "*" + pattern + "*"
+	}
+	expr, err := globToRegexp(pattern)
+	if err != nil {
+		return nil, err
+	}
+	return regexp.MustCompile("(?is)^" + expr + "$").MatchString, nil
+}
+
+// globToRegexp translates a glob into a regular expression, with '\' escaping the next character
+func globToRegexp(pattern string) (string, error) {
+	var expr strings.Builder
+	chars := []rune(pattern)
+	for i := 0; i < len(chars); i++ {
+		switch c := chars[i]; c {
+		case '*':
+			expr.WriteString(".*")
+		case '?':
+			expr.WriteString(".")
+		case '\\':
+			if i++; i == len(chars) {
+				return "", ErrBadGlob
+			}
+			expr.WriteString(regexp.QuoteMeta(string(chars[i])))
+		case '[':
+			end, class, err := globClass(chars, i+1)
+			if err != nil {
+				return "", err
+			}
+			expr.WriteString(class)
+			i = end
+		default:
+			expr.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	return expr.String(), nil
+}
+
+// globClass translates the character class starting after a '[', returning where it ends
+func globClass(chars []rune, start int) (int, string, error) {
+	var class strings.Builder
+	class.WriteRune('[')
+	i := start
+	if i < len(chars) && (chars[i] == '!' || chars[i] == '^') {
+		class.WriteRune('^')
+		i++
+	}
+	first := i
+	for ; i < len(chars); i++ {
+		c, escaped := chars[i], false
+		switch {
+		case c == ']' && i > first:
+			class.WriteRune(']')
+			return i, class.String(), nil
+		case c == ']':
+			return 0, "", ErrBadGlob
+		case c == '\\':
+			if i++; i == len(chars) {
+				return 0, "", ErrBadGlob
+			}
+			c, escaped = chars[i], true
+		}
+		if c == '-' && !escaped && i > first && i+1 < len(chars) && chars[i+1] != ']' {
+			class.WriteRune('-')
+			continue
+		}
+		if strings.ContainsRune(`\[]^-`, c) {
+			class.WriteRune('\\')
+		}
+		class.WriteRune(c)
+	}
+	return 0, "", ErrBadGlob
+}
+
+// Component matches a component, any of its sub-components, or a glob of them
+func Component(pattern string) Matcher {
+	return func(s string) bool {
+		if s == pattern || strings.HasPrefix(s, pattern+".") {
+			return true
+		}
+		matched, _ := path.Match(pattern, s)
+		return matched
+	}
+}
+
+// Any matches if any of the matchers do
+func Any(matchers ...Matcher) Matcher {
+	return func(s string) bool {
+		for _, match := range matchers {
+			if match(s) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// Regexp matches regular expressions, ignoring case
+func Regexp(pattern string) (Matcher, error) {
+	re, err := regexp.Compile("(?i)" + pattern)
+	if err != nil {
+		return nil, err
+	}
+	return re.MatchString, nil
+}
+
+// MinFuzzyLength is the shortest pattern Fuzzy accepts, since a shorter one is a subsequence
+// of, or only a couple of edits away from, almost every name
+const MinFuzzyLength = 3
+
+// ErrShortFuzzy means a fuzzy pattern is shorter than MinFuzzyLength
+var ErrShortFuzzy = fmt.Errorf("fuzzy patterns need at least %d characters", MinFuzzyLength)
+
+// Fuzzy matches strings which contain every character of the pattern in order,
+// or which are only a few edits away from it, ignoring case
+func Fuzzy(pattern string) (Matcher, error) {
+	pattern = strings.ToLower(pattern)
+	if len([]rune(pattern)) < MinFuzzyLength {
+		return nil, ErrShortFuzzy
+	}
+	return func(s string) bool {
+		s = strings.ToLower(s)
+		return Subsequence(pattern, s) || Distance(pattern, s) <= Tolerance(pattern)
+	}, nil
+}
+
+// Subsequence checks if every character of pattern appears in s, in order
+func Subsequence(pattern, s string) bool {
+	rest := []rune(s)
+	for _, c := range pattern {
+		i := 0
+		for i < len(rest) && rest[i] != c {
+			i++
+		}
+		if i == len(rest) {
+			return false
+		}
+		rest = rest[i+1:]
+	}
+	return true
+}
+
+// Distance is the Levenshtein distance between two strings
+func Distance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = minOf(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(rb)]
+}
+
+func minOf(values ...int) int {
+	least := values[0]
+	for _, value := range values[1:] {
+		if value < least {
+			least = value
+		}
+	}
+	return least
+}
+
+// Tolerance is the number of edits allowed before a name is too different to suggest
+func Tolerance(name string) int {
+	if tolerance := len(name) / 3; tolerance > 2 {
+		return tolerance
+	}
+	return 2
+}
+
+// Suggest finds up to max names which are close to a misspelled name, closest first
+func Suggest(names []string, name string, max int) []string {
+	type candidate struct {
+		name     string
+		distance int
+	}
+	var candidates []candidate
+	tolerance := Tolerance(name)
+	for _, other := range names {
+		distance := Distance(name, other)
+		if distance <= tolerance || strings.Contains(other, name) {
+			candidates = append(candidates, candidate{other, distance})
+		}
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		if candidates[i].distance != candidates[j].distance {
+			return candidates[i].distance < candidates[j].distance
+		}
+		return candidates[i].name < candidates[j].name
+	})
+	var suggestions []string
+	for i := 0; i < len(candidates) && i < max; i++ {
+		suggestions = append(suggestions, candidates[i].name)
+	}
+	return suggestions
+}
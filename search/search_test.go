@@ -0,0 +1,212 @@
+//
+// Copyright 2018-2021 Bryan T. Meyers <root@datadrake.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package search
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestGlob(t *testing.T) {
+	tests := []struct {
+		pattern string
+		s       string
+		matched bool
+	}{
+		{"ssl", "openssl", true},
+		{"SSL", "OpenSSL-devel", true},
+		{"ssl", "curl", false},
+		{"lib*", "libgit2", true},
+		{"lib*", "git-lib", false},
+		{"*lib", "zlib", true},
+		{"li?", "lib", true},
+		{"li?", "libs", false},
+		{"*", "", true},
+		{"*/bin/*", "/usr/bin/curl", true},
+		{"/usr/*", "/usr/lib64/libz.so", true},
+		{"/usr/?ib64/*", "/usr/lib64/libz.so", true},
+		{"[a-c]url", "curl", true},
+		{"[a-c]url", "purl", false},
+		{"[!a-c]url", "purl", true},
+		{"[^a-c]url", "curl", false},
+		{"[-x]", "-", true},
+		{"[a\\-c]", "b", false},
+		{"[a\\-c]", "-", true},
+		{"a\\*", "a*", true},
+		{"a\\*", "ab", false},
+		{"a.b", "axb", false},
+		{"(x)+", "(x)+", true},
+	}
+	for _, test := range tests {
+		match, err := Glob(test.pattern)
+		if err != nil {
+			t.Errorf("Glob(%q): unexpected error: %s", test.pattern, err)
+			continue
+		}
+		if matched := match(test.s); matched != test.matched {
+			t.Errorf("Glob(%q) on %q = %t, expected %t", test.pattern, test.s, matched, test.matched)
+		}
+	}
+	for _, pattern := range []string{"[abc", "[]", "[!]", "a*\\", "[a\\"} {
+		if _, err := Glob(pattern); err != ErrBadGlob {
+			t.Errorf("Glob(%q): expected ErrBadGlob, found %v", pattern, err)
+		}
+	}
+}
+
+func TestRegexp(t *testing.T) {
+	tests := []struct {
+		pattern string
+		s       string
+		matched bool
+	}{
+		{"^lib", "libgit2", true},
+		{"^LIB", "libgit2", true},
+		{"^lib", "zlib", false},
+		{"ssl$", "OpenSSL", true},
+		{"g.t", "GIT", true},
+	}
+	for _, test := range tests {
+		match, err := Regexp(test.pattern)
+		if err != nil {
+			t.Errorf("Regexp(%q): unexpected error: %s", test.pattern, err)
+			continue
+		}
+		if matched := match(test.s); matched != test.matched {
+			t.Errorf("Regexp(%q) on %q = %t, expected %t", test.pattern, test.s, matched, test.matched)
+		}
+	}
+	if _, err := Regexp("("); err == nil {
+		t.Error("Regexp(\"(\"): expected an error")
+	}
+}
+
+func TestComponent(t *testing.T) {
+	tests := []struct {
+		pattern string
+		s       string
+		matched bool
+	}{
+		{"desktop", "desktop", true},
+		{"desktop", "desktop.gnome", true},
+		{"desktop", "desktop.gnome.core", true},
+		{"desktop", "desktops", false},
+		{"desktop.*", "desktop.gnome", true},
+		{"desktop.*", "desktop", false},
+		{"system.base", "system", false},
+	}
+	for _, test := range tests {
+		if matched := Component(test.pattern)(test.s); matched != test.matched {
+			t.Errorf("Component(%q) on %q = %t, expected %t", test.pattern, test.s, matched, test.matched)
+		}
+	}
+}
+
+func TestFuzzy(t *testing.T) {
+	tests := []struct {
+		pattern string
+		s       string
+		matched bool
+	}{
+		{"gt2", "libgit2", true},
+		{"GIT", "libgit2", true},
+		{"tig", "git", true},
+		{"tgi", "libgit2", false},
+		{"zlbi", "zlib", true},
+		{"lbgt", "libgit2", true},
+		{"openssl", "curl", false},
+		{"curl", "libgit2", false},
+	}
+	for _, test := range tests {
+		match, err := Fuzzy(test.pattern)
+		if err != nil {
+			t.Errorf("Fuzzy(%q): unexpected error: %s", test.pattern, err)
+			continue
+		}
+		if matched := match(test.s); matched != test.matched {
+			t.Errorf("Fuzzy(%q) on %q = %t, expected %t", test.pattern, test.s, matched, test.matched)
+		}
+	}
+	for _, pattern := range []string{"", "g", "gt", "ü1"} {
+		if _, err := Fuzzy(pattern); err != ErrShortFuzzy {
+			t.Errorf("Fuzzy(%q): expected ErrShortFuzzy, found %v", pattern, err)
+		}
+	}
+}
+
+func TestDistance(t *testing.T) {
+	tests := []struct {
+		a, b     string
+		distance int
+	}{
+		{"", "", 0},
+		{"", "abc", 3},
+		{"abc", "", 3},
+		{"zlib", "zlib", 0},
+		{"zlib", "zlbi", 2},
+		{"kitten", "sitting", 3},
+		{"flaw", "lawn", 2},
+		{"über", "uber", 1},
+	}
+	for _, test := range tests {
+		if distance := Distance(test.a, test.b); distance != test.distance {
+			t.Errorf("Distance(%q, %q) = %d, expected %d", test.a, test.b, distance, test.distance)
+		}
+		if distance := Distance(test.b, test.a); distance != test.distance {
+			t.Errorf("Distance(%q, %q) = %d, expected %d", test.b, test.a, distance, test.distance)
+		}
+	}
+}
+
+func TestTolerance(t *testing.T) {
+	tests := []struct {
+		name      string
+		tolerance int
+	}{
+		{"", 2},
+		{"git", 2},
+		{"openssl", 2},
+		{"libgit2-devel", 4},
+	}
+	for _, test := range tests {
+		if tolerance := Tolerance(test.name); tolerance != test.tolerance {
+			t.Errorf("Tolerance(%q) = %d, expected %d", test.name, tolerance, test.tolerance)
+		}
+	}
+}
+
+func TestSuggest(t *testing.T) {
+	names := []string{"curl", "git", "libgit2", "openssl", "openssl-devel", "zlib", "zlib-devel"}
+	tests := []struct {
+		name        string
+		max         int
+		suggestions []string
+	}{
+		{"zlbi", 5, []string{"zlib"}},
+		{"gti", 5, []string{"git"}},
+		{"zlib", 5, []string{"zlib", "zlib-devel"}},
+		{"openssl", 1, []string{"openssl"}},
+		{"git", 5, []string{"git", "libgit2"}},
+		{"kernel", 5, nil},
+		{"zlib", 0, nil},
+	}
+	for _, test := range tests {
+		if suggestions := Suggest(names, test.name, test.max); !reflect.DeepEqual(suggestions, test.suggestions) {
+			t.Errorf("Suggest(%q, %d) = %q, expected %q", test.name, test.max, suggestions, test.suggestions)
+		}
+	}
+}
@@ -0,0 +1,43 @@
+//
+// Copyright 2018-2021 Bryan T. Meyers <root@datadrake.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package storage
+
+// Dependency is Storage's Representation of an edge where Left depends on Right
+type Dependency struct {
+	Left    string `db:"left_name" json:"left"`
+	Right   string `db:"right_name" json:"right"`
+	Release int    `db:"rel" json:"release"`
+}
+
+// Dependencies is a list of Dependency structs
+type Dependencies []Dependency
+
+// Kinds of provided names
+const (
+	ProvidesPkgConfig   = "pkgconfig"
+	ProvidesPkgConfig32 = "pkgconfig32"
+)
+
+// Provide is Storage's Representation of a name provided by a package, like a pkg-config module
+type Provide struct {
+	Package string `db:"package" json:"package"`
+	Kind    string `db:"kind" json:"kind"`
+	Name    string `db:"name" json:"name"`
+}
+
+// Provides is a list of Provide structs
+type Provides []Provide
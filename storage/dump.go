@@ -0,0 +1,130 @@
+//
+// Copyright 2018-2021 Bryan T. Meyers <root@datadrake.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// DumpVersion must be increased whenever the layout of a Dump changes
+const DumpVersion = 1
+
+// Dump is the entire contents of a store, in a form any Store can restore from
+type Dump struct {
+	Version      int          `json:"version"`
+	Packages     Packages     `json:"packages"`
+	Dependencies Dependencies `json:"dependencies"`
+	Provides     Provides     `json:"provides"`
+	ToDo         []ToDo       `json:"todo"`
+	Timing       []Timing     `json:"timing"`
+}
+
+// ToDo is a single entry in the todo list
+type ToDo struct {
+	Name string `db:"name" json:"name"`
+	Done bool   `db:"done" json:"done"`
+}
+
+// Timing is the build history of a package, where Started is set while a build is running
+// and Duration is set once one has been recorded, both in seconds
+type Timing struct {
+	Name     string `db:"name" json:"name"`
+	Started  *int64 `db:"started" json:"started,omitempty"`
+	Duration *int   `db:"duration" json:"duration,omitempty"`
+}
+
+// invalid describes why a dump cannot be restored
+func invalid(format string, args ...interface{}) error {
+	return fmt.Errorf("%w: %s", ErrInvalidDump, fmt.Sprintf(format, args...))
+}
+
+// Check makes sure a dump is the current version and that everything in it refers to known packages
+func (d *Dump) Check() error {
+	if d.Version != DumpVersion {
+		return invalid("version %d is not supported, expected %d", d.Version, DumpVersion)
+	}
+	known := make(map[string]bool, len(d.Packages))
+	for _, pkg := range d.Packages {
+		if len(pkg.Name) == 0 {
+			return invalid("package without a name")
+		}
+		if known[pkg.Name] {
+			return invalid("package '%s' is listed more than once", pkg.Name)
+		}
+		known[pkg.Name] = true
+	}
+	for _, dep := range d.Dependencies {
+		if !known[dep.Left] || !known[dep.Right] {
+			return invalid("dependency of '%s' on '%s' refers to an unknown package", dep.Left, dep.Right)
+		}
+	}
+	for _, provide := range d.Provides {
+		if !known[provide.Package] {
+			return invalid("'%s' is provided by unknown package '%s'", provide.Name, provide.Package)
+		}
+		if provide.Kind != ProvidesPkgConfig && provide.Kind != ProvidesPkgConfig32 {
+			return invalid("'%s' is provided as unknown kind '%s'", provide.Name, provide.Kind)
+		}
+	}
+	pending := make(map[string]bool)
+	for _, item := range d.ToDo {
+		if !known[item.Name] {
+			return invalid("todo list has unknown package '%s'", item.Name)
+		}
+		if !item.Done && pending[item.Name] {
+			return invalid("package '%s' is queued more than once", item.Name)
+		}
+		pending[item.Name] = pending[item.Name] || !item.Done
+	}
+	timed := make(map[string]bool, len(d.Timing))
+	for _, record := range d.Timing {
+		if len(record.Name) == 0 {
+			return invalid("timing without a package name")
+		}
+		if timed[record.Name] {
+			return invalid("timing for '%s' is listed more than once", record.Name)
+		}
+		if record.Duration != nil && *record.Duration < 0 {
+			return invalid("timing for '%s' has a negative duration", record.Name)
+		}
+		timed[record.Name] = true
+	}
+	return nil
+}
+
+// ReadDump decodes a dump, refusing any with fields it does not know about or which fail Check
+func ReadDump(r io.Reader) (*Dump, error) {
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+	var d Dump
+	if err := dec.Decode(&d); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidDump, err.Error())
+	}
+	if err := d.Check(); err != nil {
+		return nil, err
+	}
+	return &d, nil
+}
+
+// Write encodes a dump as indented JSON
+func (d *Dump) Write(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "    ")
+	return enc.Encode(d)
+}
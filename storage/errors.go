@@ -0,0 +1,76 @@
+//
+// Copyright 2018-2021 Bryan T. Meyers <root@datadrake.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Errors returned by every Store, wrapped in a PackageError, a MissingError or a description
+var (
+	// ErrPackageNotFound means a package is not in the store, or the store needs to be updated
+	ErrPackageNotFound = errors.New("does not exist")
+	// ErrNotProvided means no package provides a name
+	ErrNotProvided = errors.New("is not provided by any package")
+	// ErrAlreadyStarted means a package is already queued for a rebuild
+	ErrAlreadyStarted = errors.New("has already been queued for a rebuild")
+	// ErrNotInToDo means a package has never been queued for a rebuild
+	ErrNotInToDo = errors.New("is not in the todo list")
+	// ErrAlreadyDone means a package has already been rebuilt
+	ErrAlreadyDone = errors.New("is already marked 'Done'")
+	// ErrNegativeDuration means a build duration is less than zero
+	ErrNegativeDuration = errors.New("has a negative duration")
+	// ErrReadOnly means a change was made to a store opened read-only
+	ErrReadOnly = errors.New("store was opened read-only")
+	// ErrOutOfDate means a store has to be updated before it can be opened read-only
+	ErrOutOfDate = errors.New("store is out of date, it needs to be updated")
+	// ErrInvalidDump means a dump is from another version or does not describe a consistent store
+	ErrInvalidDump = errors.New("invalid dump")
+)
+
+// PackageError is returned when an operation on a single package fails
+type PackageError struct {
+	Name string
+	Err  error
+}
+
+// Error describes the failure in terms of the package
+func (e *PackageError) Error() string {
+	return fmt.Sprintf("Package '%s' %s", e.Name, e.Err)
+}
+
+// Unwrap allows the cause to be checked with errors.Is
+func (e *PackageError) Unwrap() error {
+	return e.Err
+}
+
+// MissingError is returned when one or more packages in a batch could not be found
+type MissingError struct {
+	Names []string
+}
+
+// Error lists every package that could not be found
+func (e *MissingError) Error() string {
+	return fmt.Sprintf("Packages do not exist: %s", strings.Join(e.Names, ", "))
+}
+
+// Unwrap allows a MissingError to be checked for ErrPackageNotFound with errors.Is
+func (e *MissingError) Unwrap() error {
+	return ErrPackageNotFound
+}
@@ -0,0 +1,896 @@
+//
+// Copyright 2018-2021 Bryan T. Meyers <root@datadrake.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package storage
+
+import (
+	"context"
+	"fmt"
+	"github.com/DataDrake/eopkg-deps/index"
+	"sync"
+	"time"
+)
+
+// MemoryStore is a backing store which keeps everything in memory, for one-shot use and testing
+type MemoryStore struct {
+	lock     sync.RWMutex
+	open     bool
+	readOnly bool
+	names    []string
+	packages map[string]*memPackage
+	todo     []todoItem
+	timing   map[string]timing
+	stamp    index.Stamp
+}
+
+// memPackage is a package along with its edges
+type memPackage struct {
+	Package
+	deps     Dependencies
+	revs     Dependencies
+	provides Provides
+}
+
+// todoItem is a single row of the todo list
+type todoItem struct {
+	name string
+	done bool
+}
+
+// timing is the record of how long a package takes to build
+type timing struct {
+	started  int64
+	running  bool
+	duration int
+	recorded bool
+}
+
+// NewMemoryStore gets a new in-memory store
+func NewMemoryStore() Store {
+	return &MemoryStore{
+		packages: make(map[string]*memPackage),
+		timing:   make(map[string]timing),
+	}
+}
+
+// Open initializes the store, ignoring the location since nothing outlives the store
+func (s *MemoryStore) Open(location string) error {
+	return s.OpenContext(context.Background(), location)
+}
+
+// OpenContext initializes the store, ignoring the location since nothing outlives the store
+func (s *MemoryStore) OpenContext(ctx context.Context, location string) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.open {
+		return fmt.Errorf("DB is already open")
+	}
+	s.open = true
+	return ctx.Err()
+}
+
+// OpenReadOnly initializes the store, which refuses any changes
+func (s *MemoryStore) OpenReadOnly(location string) error {
+	return s.OpenReadOnlyContext(context.Background(), location)
+}
+
+// OpenReadOnlyContext initializes the store, which refuses any changes
+func (s *MemoryStore) OpenReadOnlyContext(ctx context.Context, location string) error {
+	if err := s.OpenContext(ctx, location); err != nil {
+		return err
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.readOnly = true
+	return nil
+}
+
+// writable makes sure the store was not opened read-only before making changes
+func (s *MemoryStore) writable() error {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	if s.readOnly {
+		return ErrReadOnly
+	}
+	return nil
+}
+
+// find gets a package by name
+func (s *MemoryStore) find(name string) (*memPackage, error) {
+	pkg, ok := s.packages[name]
+	if !ok {
+		return nil, &PackageError{name, ErrPackageNotFound}
+	}
+	return pkg, nil
+}
+
+// GetForward returns: (lhs) -> *
+func (s *MemoryStore) GetForward(lhs string) (Packages, error) {
+	return s.GetForwardContext(context.Background(), lhs)
+}
+
+// GetForwardContext returns: (lhs) -> *
+func (s *MemoryStore) GetForwardContext(ctx context.Context, lhs string) (Packages, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	pkg, err := s.find(lhs)
+	if err != nil {
+		return nil, err
+	}
+	rhs := make(Packages, 0, len(pkg.deps))
+	for _, dep := range pkg.deps {
+		right := s.packages[dep.Right]
+		rhs = append(rhs, Package{Name: right.Name, Release: dep.Release, Current: right.Release, Component: right.Component})
+	}
+	return rhs, nil
+}
+
+// GetReverse returns: * -> (rhs)
+func (s *MemoryStore) GetReverse(rhs string) (Packages, error) {
+	return s.GetReverseContext(context.Background(), rhs)
+}
+
+// GetReverseContext returns: * -> (rhs)
+func (s *MemoryStore) GetReverseContext(ctx context.Context, rhs string) (Packages, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	pkg, err := s.find(rhs)
+	if err != nil {
+		return nil, err
+	}
+	lhs := make(Packages, 0, len(pkg.revs))
+	for _, rev := range pkg.revs {
+		left := s.packages[rev.Left]
+		lhs = append(lhs, Package{Name: left.Name, Release: rev.Release, Current: left.Release, Component: left.Component})
+	}
+	return lhs, nil
+}
+
+// GetProvides returns the names provided by a package
+func (s *MemoryStore) GetProvides(name string) (Provides, error) {
+	return s.GetProvidesContext(context.Background(), name)
+}
+
+// GetProvidesContext returns the names provided by a package
+func (s *MemoryStore) GetProvidesContext(ctx context.Context, name string) (Provides, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	pkg, err := s.find(name)
+	if err != nil {
+		return nil, err
+	}
+	return append(make(Provides, 0, len(pkg.provides)), pkg.provides...), nil
+}
+
+// WhatProvides returns the packages which provide a name
+func (s *MemoryStore) WhatProvides(name string) (Packages, error) {
+	return s.WhatProvidesContext(context.Background(), name)
+}
+
+// WhatProvidesContext returns the packages which provide a name
+func (s *MemoryStore) WhatProvidesContext(ctx context.Context, name string) (Packages, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	pkgs := make(Packages, 0)
+	if err := ctx.Err(); err != nil {
+		return pkgs, err
+	}
+	for _, pkgName := range s.names {
+		pkg := s.packages[pkgName]
+		for _, provide := range pkg.provides {
+			if provide.Name == name {
+				pkgs = append(pkgs, Package{Name: pkg.Name, Release: pkg.Release})
+				break
+			}
+		}
+	}
+	if len(pkgs) == 0 {
+		return pkgs, &PackageError{name, ErrNotProvided}
+	}
+	return pkgs, nil
+}
+
+// GetPackages returns every package
+func (s *MemoryStore) GetPackages() (Packages, error) {
+	return s.GetPackagesContext(context.Background())
+}
+
+// GetPackagesContext returns every package
+func (s *MemoryStore) GetPackagesContext(ctx context.Context) (Packages, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	if err := ctx.Err(); err != nil {
+		return make(Packages, 0), err
+	}
+	return s.list(), nil
+}
+
+// list gets every package, without any of its edges
+func (s *MemoryStore) list() Packages {
+	pkgs := make(Packages, 0, len(s.names))
+	for _, name := range s.names {
+		pkg := s.packages[name]
+		pkgs = append(pkgs, Package{Name: pkg.Name, Release: pkg.Release, Summary: pkg.Summary, Component: pkg.Component})
+	}
+	return pkgs
+}
+
+// GetGraph returns every package and every dependency between them
+func (s *MemoryStore) GetGraph() (Packages, Dependencies, error) {
+	return s.GetGraphContext(context.Background())
+}
+
+// GetGraphContext returns every package and every dependency between them
+func (s *MemoryStore) GetGraphContext(ctx context.Context) (Packages, Dependencies, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	if err := ctx.Err(); err != nil {
+		return make(Packages, 0), nil, err
+	}
+	pkgs := s.list()
+	deps := make(Dependencies, 0)
+	for _, name := range s.names {
+		deps = append(deps, s.packages[name].deps...)
+	}
+	return pkgs, deps, nil
+}
+
+// GetPackage returns a single package by name
+func (s *MemoryStore) GetPackage(name string) (Package, error) {
+	return s.GetPackageContext(context.Background(), name)
+}
+
+// GetPackageContext returns a single package by name
+func (s *MemoryStore) GetPackageContext(ctx context.Context, name string) (Package, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	if err := ctx.Err(); err != nil {
+		return Package{}, err
+	}
+	pkg, err := s.find(name)
+	if err != nil {
+		return Package{}, err
+	}
+	return Package{Name: pkg.Name, Release: pkg.Release, Summary: pkg.Summary, Component: pkg.Component}, nil
+}
+
+// SetRelease records a new release number for an existing package
+func (s *MemoryStore) SetRelease(name string, release int) error {
+	return s.SetReleaseContext(context.Background(), name, release)
+}
+
+// SetReleaseContext records a new release number for an existing package
+func (s *MemoryStore) SetReleaseContext(ctx context.Context, name string, release int) error {
+	if err := s.writable(); err != nil {
+		return err
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	pkg, err := s.find(name)
+	if err != nil {
+		return err
+	}
+	pkg.Release = release
+	return nil
+}
+
+// Rebuilt records a new release number for an existing package and, if it is pending in the todo list,
+// marks it as complete and queues its reverse deps, all or nothing
+func (s *MemoryStore) Rebuilt(name string, release int) (bool, error) {
+	return s.RebuiltContext(context.Background(), name, release)
+}
+
+// RebuiltContext records a new release number for an existing package and, if it is pending in the todo list,
+// marks it as complete and queues its reverse deps, all or nothing
+func (s *MemoryStore) RebuiltContext(ctx context.Context, name string, release int) (bool, error) {
+	if err := s.writable(); err != nil {
+		return false, err
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+	pkg, err := s.find(name)
+	if err != nil {
+		return false, err
+	}
+	queued := pending(s.todo, name)
+	if queued {
+		todo := append([]todoItem(nil), s.todo...)
+		records := s.copyTiming()
+		if todo, err = s.doneToDo(todo, records, name, true, time.Now().Unix()); err != nil {
+			return false, err
+		}
+		s.todo, s.timing = todo, records
+	}
+	pkg.Release = release
+	return queued, nil
+}
+
+// pending checks if a package is queued and not yet rebuilt
+func pending(todo []todoItem, name string) bool {
+	for _, item := range todo {
+		if item.name == name && !item.done {
+			return true
+		}
+	}
+	return false
+}
+
+// copyTiming copies the timing records, so a failed change can be thrown away
+func (s *MemoryStore) copyTiming() map[string]timing {
+	records := make(map[string]timing, len(s.timing))
+	for name, record := range s.timing {
+		records[name] = record
+	}
+	return records
+}
+
+// StartToDo adds new packages to the todo list, all or nothing
+func (s *MemoryStore) StartToDo(names ...string) error {
+	return s.StartToDoContext(context.Background(), names...)
+}
+
+// StartToDoContext adds new packages to the todo list, all or nothing
+func (s *MemoryStore) StartToDoContext(ctx context.Context, names ...string) error {
+	if err := s.writable(); err != nil {
+		return err
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	todo := append([]todoItem(nil), s.todo...)
+	records := s.copyTiming()
+	now := time.Now().Unix()
+	var missing []string
+	for _, name := range names {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if pending(todo, name) {
+			return &PackageError{name, ErrAlreadyStarted}
+		}
+		if _, err := s.find(name); err != nil {
+			missing = append(missing, name)
+			continue
+		}
+		todo = append(todo, todoItem{name, false})
+		record := records[name]
+		record.started, record.running = now, true
+		records[name] = record
+	}
+	if len(missing) > 0 {
+		return &MissingError{missing}
+	}
+	s.todo, s.timing = todo, records
+	return nil
+}
+
+// ClaimToDo records that the builds of queued packages have begun
+func (s *MemoryStore) ClaimToDo(names ...string) error {
+	return s.ClaimToDoContext(context.Background(), names...)
+}
+
+// ClaimToDoContext records that the builds of queued packages have begun
+func (s *MemoryStore) ClaimToDoContext(ctx context.Context, names ...string) error {
+	if err := s.writable(); err != nil {
+		return err
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	records := s.copyTiming()
+	now := time.Now().Unix()
+	for _, name := range names {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if !pending(s.todo, name) {
+			return &PackageError{name, ErrNotInToDo}
+		}
+		record := records[name]
+		record.started, record.running = now, true
+		records[name] = record
+	}
+	s.timing = records
+	return nil
+}
+
+// DoneToDo marks packages as complete and optionally queues their reverse deps, all or nothing
+func (s *MemoryStore) DoneToDo(Continue bool, names ...string) error {
+	return s.DoneToDoContext(context.Background(), Continue, names...)
+}
+
+// DoneToDoContext marks packages as complete and optionally queues their reverse deps, all or nothing
+func (s *MemoryStore) DoneToDoContext(ctx context.Context, Continue bool, names ...string) error {
+	if err := s.writable(); err != nil {
+		return err
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	todo := append([]todoItem(nil), s.todo...)
+	records := s.copyTiming()
+	now := time.Now().Unix()
+	for _, name := range names {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		var err error
+		if todo, err = s.doneToDo(todo, records, name, Continue, now); err != nil {
+			return err
+		}
+	}
+	s.todo, s.timing = todo, records
+	return nil
+}
+
+func (s *MemoryStore) doneToDo(todo []todoItem, records map[string]timing, name string, Continue bool, now int64) ([]todoItem, error) {
+	queued := false
+	for _, item := range todo {
+		queued = queued || item.name == name
+	}
+	if !queued {
+		return todo, &PackageError{name, ErrNotInToDo}
+	}
+	if !pending(todo, name) {
+		return todo, &PackageError{name, ErrAlreadyDone}
+	}
+	for i := range todo {
+		if todo[i].name == name {
+			todo[i].done = true
+		}
+	}
+	if record, ok := records[name]; ok && record.running {
+		record.duration, record.recorded = int(now-record.started), true
+		record.started, record.running = 0, false
+		records[name] = record
+	}
+	if !Continue {
+		return todo, nil
+	}
+	pkg, err := s.find(name)
+	if err != nil {
+		return todo, err
+	}
+	listed := make(map[string]bool, len(todo))
+	for _, item := range todo {
+		listed[item.name] = true
+	}
+	for _, rev := range pkg.revs {
+		if !listed[rev.Left] {
+			listed[rev.Left] = true
+			todo = append(todo, todoItem{rev.Left, false})
+		}
+	}
+	return todo, nil
+}
+
+// GetToDo gets the currently unblocked packages to rebuild
+func (s *MemoryStore) GetToDo() (Packages, int, int, error) {
+	return s.GetToDoContext(context.Background())
+}
+
+// GetToDoContext gets the currently unblocked packages to rebuild
+func (s *MemoryStore) GetToDoContext(ctx context.Context) (Packages, int, int, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	unblocked := make(Packages, 0)
+	if err := ctx.Err(); err != nil {
+		return unblocked, 0, 0, err
+	}
+	// Anything depending on a pending package has to wait for it
+	blocked := make(map[string]bool)
+	pending := make(map[string]bool)
+	for _, item := range s.todo {
+		if item.done {
+			continue
+		}
+		pending[item.name] = true
+		for _, rev := range s.packages[item.name].revs {
+			blocked[rev.Left] = true
+		}
+	}
+	count, done := 0, 0
+	for _, item := range s.todo {
+		if item.done {
+			done++
+			continue
+		}
+		count++
+		if !blocked[item.name] {
+			unblocked = append(unblocked, Package{
+				Name:      item.name,
+				Component: s.packages[item.name].Component,
+				Impact:    s.impact(item.name, pending),
+			})
+		}
+	}
+	return unblocked, count, done, nil
+}
+
+// impact counts the pending packages which depend on a package, directly or not
+func (s *MemoryStore) impact(name string, pending map[string]bool) int {
+	seen := map[string]bool{name: true}
+	level := []string{name}
+	count := 0
+	for len(level) > 0 {
+		var next []string
+		for _, right := range level {
+			for _, rev := range s.packages[right].revs {
+				if seen[rev.Left] {
+					continue
+				}
+				seen[rev.Left] = true
+				if pending[rev.Left] {
+					count++
+				}
+				next = append(next, rev.Left)
+			}
+		}
+		level = next
+	}
+	return count
+}
+
+// WorstToDo gets a worst-case list of packages to rebuild and the minimum depth each is reached at,
+// going no deeper than maxDepth (unless zero) and not rebuilding or traversing past excluded packages
+func (s *MemoryStore) WorstToDo(name string, maxDepth int, exclude ...string) (Packages, error) {
+	return s.WorstToDoContext(context.Background(), name, maxDepth, exclude...)
+}
+
+// WorstToDoContext gets a worst-case list of packages to rebuild and the minimum depth each is reached at,
+// going no deeper than maxDepth (unless zero) and not rebuilding or traversing past excluded packages
+func (s *MemoryStore) WorstToDoContext(ctx context.Context, name string, maxDepth int, exclude ...string) (Packages, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	list := make(Packages, 0)
+	if _, err := s.find(name); err != nil {
+		return list, err
+	}
+	// No path without a cycle can be longer than the number of packages
+	if maxDepth <= 0 {
+		maxDepth = len(s.packages)
+	}
+	excluded := make(map[string]bool, len(exclude))
+	for _, name := range exclude {
+		excluded[name] = true
+	}
+	// Breadth-first, so each package is first reached at its minimum depth
+	depths := make(map[string]int)
+	level := []string{name}
+	for depth := 1; depth <= maxDepth && len(level) > 0; depth++ {
+		if err := ctx.Err(); err != nil {
+			return list, err
+		}
+		var next []string
+		for _, right := range level {
+			for _, rev := range s.packages[right].revs {
+				if _, seen := depths[rev.Left]; seen || excluded[rev.Left] {
+					continue
+				}
+				depths[rev.Left] = depth
+				next = append(next, rev.Left)
+			}
+		}
+		level = next
+	}
+	for _, name := range s.names {
+		if depth, ok := depths[name]; ok {
+			list = append(list, Package{Name: name, Component: s.packages[name].Component, Depth: depth})
+		}
+	}
+	return list, nil
+}
+
+// GetPending returns every queued package and the dependencies between them
+func (s *MemoryStore) GetPending() (Packages, Dependencies, error) {
+	return s.GetPendingContext(context.Background())
+}
+
+// GetPendingContext returns every queued package and the dependencies between them
+func (s *MemoryStore) GetPendingContext(ctx context.Context) (Packages, Dependencies, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	pkgs := make(Packages, 0)
+	if err := ctx.Err(); err != nil {
+		return pkgs, nil, err
+	}
+	queued := make(map[string]bool)
+	for _, item := range s.todo {
+		if !item.done {
+			queued[item.name] = true
+			pkgs = append(pkgs, Package{Name: item.name})
+		}
+	}
+	deps := make(Dependencies, 0)
+	for _, pkg := range pkgs {
+		for _, dep := range s.packages[pkg.Name].deps {
+			if queued[dep.Right] {
+				deps = append(deps, dep)
+			}
+		}
+	}
+	return pkgs, deps, nil
+}
+
+// GetDurations returns every package with a recorded build duration
+func (s *MemoryStore) GetDurations() (Packages, error) {
+	return s.GetDurationsContext(context.Background())
+}
+
+// GetDurationsContext returns every package with a recorded build duration
+func (s *MemoryStore) GetDurationsContext(ctx context.Context) (Packages, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	pkgs := make(Packages, 0)
+	if err := ctx.Err(); err != nil {
+		return pkgs, err
+	}
+	for name, record := range s.timing {
+		if record.recorded {
+			pkgs = append(pkgs, Package{Name: name, Duration: record.duration})
+		}
+	}
+	return pkgs, nil
+}
+
+// SetDurations records the build duration of each package, replacing any existing records
+func (s *MemoryStore) SetDurations(pkgs Packages) error {
+	return s.SetDurationsContext(context.Background(), pkgs)
+}
+
+// SetDurationsContext records the build duration of each package, replacing any existing records
+func (s *MemoryStore) SetDurationsContext(ctx context.Context, pkgs Packages) error {
+	if err := s.writable(); err != nil {
+		return err
+	}
+	if err := checkDurations(pkgs); err != nil {
+		return err
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	for _, pkg := range pkgs {
+		record := s.timing[pkg.Name]
+		record.duration, record.recorded = pkg.Duration, true
+		s.timing[pkg.Name] = record
+	}
+	return nil
+}
+
+// ResetToDo clears the todo list
+func (s *MemoryStore) ResetToDo() error {
+	return s.ResetToDoContext(context.Background())
+}
+
+// ResetToDoContext clears the todo list
+func (s *MemoryStore) ResetToDoContext(ctx context.Context) error {
+	if err := s.writable(); err != nil {
+		return err
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	s.todo = nil
+	for name, record := range s.timing {
+		record.started, record.running = 0, false
+		s.timing[name] = record
+	}
+	return nil
+}
+
+// Update rebuilds the store from an index, walking it only once
+func (s *MemoryStore) Update(i index.Walker) error {
+	return s.UpdateContext(context.Background(), i)
+}
+
+// UpdateContext rebuilds the store from an index, walking it only once
+func (s *MemoryStore) UpdateContext(ctx context.Context, i index.Walker) error {
+	if err := s.writable(); err != nil {
+		return err
+	}
+	var names []string
+	packages := make(map[string]*memPackage)
+	deps, provides, err := walkImportable(ctx, i, func(pkg Package) error {
+		names = append(names, pkg.Name)
+		packages[pkg.Name] = &memPackage{Package: pkg}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	for _, provide := range provides {
+		provider := packages[provide.Package]
+		provider.provides = append(provider.provides, provide)
+	}
+	for _, dep := range deps {
+		left, right := packages[dep.Left], packages[dep.Right]
+		left.deps = append(left.deps, dep)
+		right.revs = append(right.revs, dep)
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.names, s.packages, s.todo, s.stamp = names, packages, nil, index.Stamp{}
+	return nil
+}
+
+// Dump gets the entire contents of the store
+func (s *MemoryStore) Dump() (*Dump, error) {
+	return s.DumpContext(context.Background())
+}
+
+// DumpContext gets the entire contents of the store
+func (s *MemoryStore) DumpContext(ctx context.Context) (*Dump, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	d := &Dump{
+		Version:      DumpVersion,
+		Packages:     s.list(),
+		Dependencies: make(Dependencies, 0),
+		Provides:     make(Provides, 0),
+		ToDo:         make([]ToDo, 0, len(s.todo)),
+		Timing:       make([]Timing, 0, len(s.timing)),
+	}
+	for _, name := range s.names {
+		d.Dependencies = append(d.Dependencies, s.packages[name].deps...)
+		d.Provides = append(d.Provides, s.packages[name].provides...)
+	}
+	for _, item := range s.todo {
+		d.ToDo = append(d.ToDo, ToDo{item.name, item.done})
+	}
+	for name, record := range s.timing {
+		entry := Timing{Name: name}
+		if record.running {
+			started := record.started
+			entry.Started = &started
+		}
+		if record.recorded {
+			duration := record.duration
+			entry.Duration = &duration
+		}
+		d.Timing = append(d.Timing, entry)
+	}
+	return d, nil
+}
+
+// Restore replaces the entire contents of the store with a dump, all or nothing
+func (s *MemoryStore) Restore(d *Dump) error {
+	return s.RestoreContext(context.Background(), d)
+}
+
+// RestoreContext replaces the entire contents of the store with a dump, all or nothing
+func (s *MemoryStore) RestoreContext(ctx context.Context, d *Dump) error {
+	if err := s.writable(); err != nil {
+		return err
+	}
+	if err := d.Check(); err != nil {
+		return err
+	}
+	names := make([]string, 0, len(d.Packages))
+	packages := make(map[string]*memPackage, len(d.Packages))
+	for _, pkg := range d.Packages {
+		names = append(names, pkg.Name)
+		packages[pkg.Name] = &memPackage{
+			Package: Package{Name: pkg.Name, Release: pkg.Release, Summary: pkg.Summary, Component: pkg.Component},
+		}
+	}
+	for _, dep := range d.Dependencies {
+		packages[dep.Left].deps = append(packages[dep.Left].deps, dep)
+		packages[dep.Right].revs = append(packages[dep.Right].revs, dep)
+	}
+	for _, provide := range d.Provides {
+		packages[provide.Package].provides = append(packages[provide.Package].provides, provide)
+	}
+	todo := make([]todoItem, 0, len(d.ToDo))
+	for _, item := range d.ToDo {
+		todo = append(todo, todoItem{item.Name, item.Done})
+	}
+	records := make(map[string]timing, len(d.Timing))
+	for _, entry := range d.Timing {
+		var record timing
+		if entry.Started != nil {
+			record.started, record.running = *entry.Started, true
+		}
+		if entry.Duration != nil {
+			record.duration, record.recorded = *entry.Duration, true
+		}
+		records[entry.Name] = record
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.names, s.packages, s.todo, s.timing = names, packages, todo, records
+	s.stamp = index.Stamp{}
+	return nil
+}
+
+// Close deinitializes the store
+func (s *MemoryStore) Close() error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if !s.open {
+		return fmt.Errorf("DB is alread closed")
+	}
+	s.open = false
+	return nil
+}
+
+// SetClosure does nothing besides checking the store is writable, since traversing in memory is already fast
+func (s *MemoryStore) SetClosure(enabled bool) error {
+	return s.SetClosureContext(context.Background(), enabled)
+}
+
+// SetClosureContext does nothing besides checking the store is writable, since traversing in memory is already fast
+func (s *MemoryStore) SetClosureContext(ctx context.Context, enabled bool) error {
+	return s.writable()
+}
+
+// GetStamp gets which index file the store was last updated from, or a zero Stamp if unknown
+func (s *MemoryStore) GetStamp() (index.Stamp, error) {
+	return s.GetStampContext(context.Background())
+}
+
+// GetStampContext gets which index file the store was last updated from, or a zero Stamp if unknown
+func (s *MemoryStore) GetStampContext(ctx context.Context) (index.Stamp, error) {
+	if err := ctx.Err(); err != nil {
+		return index.Stamp{}, err
+	}
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	return s.stamp, nil
+}
+
+// SetStamp records which index file the store was last updated from, until the next Update or Restore
+func (s *MemoryStore) SetStamp(stamp index.Stamp) error {
+	return s.SetStampContext(context.Background(), stamp)
+}
+
+// SetStampContext records which index file the store was last updated from, until the next Update or Restore
+func (s *MemoryStore) SetStampContext(ctx context.Context, stamp index.Stamp) error {
+	if err := s.writable(); err != nil {
+		return err
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.stamp = stamp
+	return nil
+}
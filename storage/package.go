@@ -18,8 +18,20 @@ package storage
 
 // Package is Storage's Representation of a Package
 type Package struct {
-	Name    string `db:"name"`
-	Release int    `db:"rel"`
+	Name    string `db:"name" json:"name"`
+	Release int    `db:"rel" json:"release"`
+	Summary string `db:"summary" json:"summary,omitempty"`
+	// Component is the part of the repository the package belongs to, like "system.base"
+	Component string `db:"component" json:"component,omitempty"`
+	Depth     int    `db:"depth" json:"depth,omitempty"`
+	// Current is the latest release of the package, where Release is the release a dependency on it
+	// applies from, as returned by GetForward and GetReverse
+	Current int `db:"current" json:"current,omitempty"`
+	// Impact is how many pending packages depend on the package, directly or through any others, as
+	// returned by GetToDo
+	Impact int `db:"impact" json:"impact,omitempty"`
+	// Duration is the recorded build time in seconds
+	Duration int `db:"duration" json:"duration,omitempty"`
 }
 
 // Packages is a sortable type for a list of Package struct
@@ -17,31 +17,44 @@
 package storage
 
 import (
+	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"github.com/DataDrake/eopkg-deps/index"
 	"github.com/jmoiron/sqlx"
 	"strings"
+	"time"
 	// Since this is the only place we will use sqlite directly
 	_ "github.com/mattn/go-sqlite3"
 )
 
 // SqliteStore is a backing store built on sqlite
 type SqliteStore struct {
-	db   *sqlx.DB
-	open bool
+	db       *sqlx.DB
+	open     bool
+	readOnly bool
 }
 
 // NewSqliteStore gets a new sqlite store
 func NewSqliteStore() Store {
-	return &SqliteStore{nil, false}
+	return &SqliteStore{nil, false, false}
 }
 
+// busyTimeout is how many milliseconds to wait for another process to finish writing before giving up
+const busyTimeout = 10000
+
+// Every write begins immediately, rather than failing when upgrading from a read while another process writes
+const readWriteDSN = "file:%s?_journal_mode=WAL&_busy_timeout=%d&_txlock=immediate"
+const readOnlyDSN = "file:%s?mode=ro&_busy_timeout=%d"
+
 const schema = `
 CREATE TABLE IF NOT EXISTS packages (
-    id   INTEGER PRIMARY KEY,
-    name TEXT,
-    rel  INTEGER
+    id      INTEGER PRIMARY KEY,
+    name    TEXT,
+    rel       INTEGER,
+    summary   TEXT,
+    component TEXT
 );
 
 CREATE TABLE IF NOT EXISTS deps (
@@ -50,56 +63,172 @@ CREATE TABLE IF NOT EXISTS deps (
     rel       INTEGER
 );
 
+CREATE TABLE IF NOT EXISTS provides (
+    package_id INTEGER,
+    kind       TEXT,
+    name       TEXT
+);
+
 CREATE TABLE IF NOT EXISTS todo (
     name       TEXT,
     package_id INTEGER,
     done       BOOLEAN
 );
+
+CREATE TABLE IF NOT EXISTS timing (
+    name     TEXT PRIMARY KEY,
+    started  INTEGER,
+    duration INTEGER
+);
+
+CREATE TABLE IF NOT EXISTS closure (
+    right_id INTEGER,
+    left_id  INTEGER,
+    depth    INTEGER,
+    PRIMARY KEY (right_id, left_id)
+) WITHOUT ROWID;
+
+CREATE TABLE IF NOT EXISTS metadata (
+    key   TEXT PRIMARY KEY,
+    value TEXT
+);
 `
 
-func (s *SqliteStore) createTables() {
-	s.db.MustExec(schema)
+// indexes are kept apart from the tables, so Update can build them after filling the tables
+const indexes = `
+CREATE INDEX IF NOT EXISTS packages_name ON packages (name);
+CREATE INDEX IF NOT EXISTS deps_left ON deps (left_id);
+CREATE INDEX IF NOT EXISTS deps_right ON deps (right_id);
+CREATE INDEX IF NOT EXISTS provides_package ON provides (package_id);
+CREATE INDEX IF NOT EXISTS provides_name ON provides (name);
+CREATE INDEX IF NOT EXISTS todo_package ON todo (package_id);
+CREATE INDEX IF NOT EXISTS todo_name ON todo (name);
+`
+
+// schemaVersion must be increased whenever the tables built by Update change
+const schemaVersion = 3
+
+func createTables(ctx context.Context, e sqlx.ExecerContext) error {
+	_, err := e.ExecContext(ctx, schema+indexes)
+	return err
+}
+
+const getVersion = "PRAGMA user_version"
+
+// migrate creates any missing tables and clears out tables from an older schema,
+// which will be rebuilt by the next Update
+func migrate(ctx context.Context, tx *sqlx.Tx) error {
+	if err := createTables(ctx, tx); err != nil {
+		return err
+	}
+	var version int
+	if err := tx.GetContext(ctx, &version, getVersion); err != nil {
+		return err
+	}
+	if version >= schemaVersion {
+		return nil
+	}
+	if _, err := tx.ExecContext(ctx, dropIndexTables); err != nil {
+		return err
+	}
+	if err := createTables(ctx, tx); err != nil {
+		return err
+	}
+	_, err := tx.ExecContext(ctx, fmt.Sprintf("PRAGMA user_version=%d", schemaVersion))
+	return err
 }
 
 // Open initializes a connection to the backend store
 func (s *SqliteStore) Open(location string) error {
+	return s.OpenContext(context.Background(), location)
+}
+
+// OpenContext initializes a connection to the backend store
+func (s *SqliteStore) OpenContext(ctx context.Context, location string) error {
 	if s.open {
 		return fmt.Errorf("DB is already open")
 	}
 	var err error
-	if s.db, err = sqlx.Open("sqlite3", location); err != nil {
+	if s.db, err = sqlx.Open("sqlite3", fmt.Sprintf(readWriteDSN, location, busyTimeout)); err != nil {
 		return err
 	}
 	s.open = true
-	s.createTables()
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if err = migrate(ctx, tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// OpenReadOnly initializes a connection to the backend store which refuses any changes
+func (s *SqliteStore) OpenReadOnly(location string) error {
+	return s.OpenReadOnlyContext(context.Background(), location)
+}
+
+// OpenReadOnlyContext initializes a connection to the backend store which refuses any changes
+func (s *SqliteStore) OpenReadOnlyContext(ctx context.Context, location string) error {
+	if s.open {
+		return fmt.Errorf("DB is already open")
+	}
+	var err error
+	if s.db, err = sqlx.Open("sqlite3", fmt.Sprintf(readOnlyDSN, location, busyTimeout)); err != nil {
+		return err
+	}
+	s.open, s.readOnly = true, true
+	// Tables can't be created or cleared out without writing, so an older DB has to be updated first
+	var version int
+	if err = s.db.GetContext(ctx, &version, getVersion); err != nil {
+		return err
+	}
+	if version < schemaVersion {
+		return ErrOutOfDate
+	}
+	return nil
+}
+
+// writable makes sure the store was not opened read-only before making changes
+func (s *SqliteStore) writable() error {
+	if s.readOnly {
+		return ErrReadOnly
+	}
 	return nil
 }
 
 const getPackage = "SELECT id FROM packages WHERE name=?"
 
-func (s *SqliteStore) nameToID(name string) (int, error) {
+func nameToID(ctx context.Context, q sqlx.QueryerContext, name string) (int, error) {
 	var id int
-	row := s.db.QueryRowx(getPackage, name)
-	if err := row.Scan(&id); err != nil {
-		return id, err
+	row := q.QueryRowxContext(ctx, getPackage, name)
+	err := row.Scan(&id)
+	if err == sql.ErrNoRows {
+		return id, &PackageError{name, ErrPackageNotFound}
 	}
-	return id, nil
+	return id, err
 }
 
 const getRHS = `
-SELECT name, rel2 AS rel FROM packages INNER JOIN (
+SELECT name, rel2 AS rel, packages.rel AS current, component FROM packages INNER JOIN (
     SELECT right_id, rel AS rel2 FROM deps WHERE left_id=?
 ) ON packages.id=right_id
 `
 
 // GetForward returns: (lhs) -> *
 func (s *SqliteStore) GetForward(lhs string) (Packages, error) {
-	lhsID, err := s.nameToID(lhs)
+	return s.GetForwardContext(context.Background(), lhs)
+}
+
+// GetForwardContext returns: (lhs) -> *
+func (s *SqliteStore) GetForwardContext(ctx context.Context, lhs string) (Packages, error) {
+	lhsID, err := nameToID(ctx, s.db, lhs)
 	if err != nil {
 		return nil, err
 	}
 	rhs := make(Packages, 0)
-	rows, err := s.db.Queryx(getRHS, lhsID)
+	rows, err := s.db.QueryxContext(ctx, getRHS, lhsID)
 	if err != nil {
 		return rhs, err
 	}
@@ -110,23 +239,28 @@ func (s *SqliteStore) GetForward(lhs string) (Packages, error) {
 		}
 		rhs = append(rhs, p)
 	}
-	return rhs, err
+	return rhs, rows.Err()
 }
 
 const getLHS = `
-SELECT name, rel2 AS rel FROM packages INNER JOIN (
+SELECT name, rel2 AS rel, packages.rel AS current, component FROM packages INNER JOIN (
     SELECT left_id, rel AS rel2 FROM deps WHERE right_id=?
 ) ON packages.id=left_id
 `
 
 // GetReverse returns: * -> (rhs)
 func (s *SqliteStore) GetReverse(rhs string) (Packages, error) {
-	rhsID, err := s.nameToID(rhs)
+	return s.GetReverseContext(context.Background(), rhs)
+}
+
+// GetReverseContext returns: * -> (rhs)
+func (s *SqliteStore) GetReverseContext(ctx context.Context, rhs string) (Packages, error) {
+	rhsID, err := nameToID(ctx, s.db, rhs)
 	if err != nil {
 		return nil, err
 	}
 	lhs := make(Packages, 0)
-	rows, err := s.db.Queryx(getLHS, rhsID)
+	rows, err := s.db.QueryxContext(ctx, getLHS, rhsID)
 	if err != nil {
 		return lhs, err
 	}
@@ -137,190 +271,825 @@ func (s *SqliteStore) GetReverse(rhs string) (Packages, error) {
 		}
 		lhs = append(lhs, p)
 	}
-	return lhs, err
+	return lhs, rows.Err()
+}
+
+const getProvides = `
+SELECT packages.name AS package, kind, provides.name AS name FROM provides
+    INNER JOIN packages ON packages.id=package_id
+    WHERE package_id=?
+`
+
+// GetProvides returns the names provided by a package
+func (s *SqliteStore) GetProvides(name string) (Provides, error) {
+	return s.GetProvidesContext(context.Background(), name)
+}
+
+// GetProvidesContext returns the names provided by a package
+func (s *SqliteStore) GetProvidesContext(ctx context.Context, name string) (Provides, error) {
+	id, err := nameToID(ctx, s.db, name)
+	if err != nil {
+		return nil, err
+	}
+	provides := make(Provides, 0)
+	err = s.db.SelectContext(ctx, &provides, getProvides, id)
+	return provides, err
+}
+
+const getProviders = `
+SELECT DISTINCT packages.name AS name, rel FROM packages
+    INNER JOIN provides ON packages.id=package_id
+    WHERE provides.name=?
+`
+
+// WhatProvides returns the packages which provide a name
+func (s *SqliteStore) WhatProvides(name string) (Packages, error) {
+	return s.WhatProvidesContext(context.Background(), name)
+}
+
+// WhatProvidesContext returns the packages which provide a name
+func (s *SqliteStore) WhatProvidesContext(ctx context.Context, name string) (Packages, error) {
+	pkgs := make(Packages, 0)
+	if err := s.db.SelectContext(ctx, &pkgs, getProviders, name); err != nil {
+		return pkgs, err
+	}
+	if len(pkgs) == 0 {
+		return pkgs, &PackageError{name, ErrNotProvided}
+	}
+	return pkgs, nil
+}
+
+const getPackages = "SELECT name, rel, summary, component FROM packages"
+
+// GetPackages returns every package
+func (s *SqliteStore) GetPackages() (Packages, error) {
+	return s.GetPackagesContext(context.Background())
+}
+
+// GetPackagesContext returns every package
+func (s *SqliteStore) GetPackagesContext(ctx context.Context) (Packages, error) {
+	pkgs := make(Packages, 0)
+	err := s.db.SelectContext(ctx, &pkgs, getPackages)
+	return pkgs, err
+}
+
+const getDeps = `
+SELECT l.name AS left_name, r.name AS right_name, deps.rel AS rel FROM deps
+    INNER JOIN packages AS l ON l.id=left_id
+    INNER JOIN packages AS r ON r.id=right_id
+`
+
+// GetGraph returns every package and every dependency between them
+func (s *SqliteStore) GetGraph() (Packages, Dependencies, error) {
+	return s.GetGraphContext(context.Background())
+}
+
+// GetGraphContext returns every package and every dependency between them
+func (s *SqliteStore) GetGraphContext(ctx context.Context) (Packages, Dependencies, error) {
+	pkgs, err := s.GetPackagesContext(ctx)
+	if err != nil {
+		return pkgs, nil, err
+	}
+	deps := make(Dependencies, 0)
+	err = s.db.SelectContext(ctx, &deps, getDeps)
+	return pkgs, deps, err
+}
+
+const getPackageByName = "SELECT name, rel, summary, component FROM packages WHERE name=?"
+
+// GetPackage returns a single package by name
+func (s *SqliteStore) GetPackage(name string) (Package, error) {
+	return s.GetPackageContext(context.Background(), name)
+}
+
+// GetPackageContext returns a single package by name
+func (s *SqliteStore) GetPackageContext(ctx context.Context, name string) (Package, error) {
+	var p Package
+	err := s.db.GetContext(ctx, &p, getPackageByName, name)
+	if err == sql.ErrNoRows {
+		err = &PackageError{name, ErrPackageNotFound}
+	}
+	return p, err
+}
+
+const setRelease = "UPDATE packages SET rel=? WHERE name=?"
+
+// SetRelease records a new release number for an existing package
+func (s *SqliteStore) SetRelease(name string, release int) error {
+	return s.SetReleaseContext(context.Background(), name, release)
+}
+
+// SetReleaseContext records a new release number for an existing package
+func (s *SqliteStore) SetReleaseContext(ctx context.Context, name string, release int) error {
+	if err := s.writable(); err != nil {
+		return err
+	}
+	result, err := s.db.ExecContext(ctx, setRelease, release, name)
+	if err != nil {
+		return err
+	}
+	count, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		return &PackageError{name, ErrPackageNotFound}
+	}
+	return nil
 }
 
 const getToDo = "SELECT count(*) FROM todo WHERE name=? AND done=FALSE"
 const insertToDo = "INSERT OR REPLACE INTO todo VALUES (?, ?, FALSE)"
 
-// StartToDo adds a new package to the todo list
-func (s *SqliteStore) StartToDo(name string) error {
-	var count int
-	if err := s.db.Get(&count, getToDo, name); err != nil {
+// StartToDo adds new packages to the todo list, all or nothing
+func (s *SqliteStore) StartToDo(names ...string) error {
+	return s.StartToDoContext(context.Background(), names...)
+}
+
+// StartToDoContext adds new packages to the todo list, all or nothing
+func (s *SqliteStore) StartToDoContext(ctx context.Context, names ...string) error {
+	if err := s.writable(); err != nil {
+		return err
+	}
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
 		return err
 	}
-	if count > 0 {
-		return fmt.Errorf("Rebuild for package '%s' has already started", name)
+	var missing []string
+	for _, name := range names {
+		var count int
+		if err = tx.GetContext(ctx, &count, getToDo, name); err != nil {
+			tx.Rollback()
+			return err
+		}
+		if count > 0 {
+			tx.Rollback()
+			return &PackageError{name, ErrAlreadyStarted}
+		}
+		id, err := nameToID(ctx, tx, name)
+		if errors.Is(err, ErrPackageNotFound) {
+			missing = append(missing, name)
+			continue
+		}
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+		if _, err = tx.ExecContext(ctx, insertToDo, name, id); err != nil {
+			tx.Rollback()
+			return err
+		}
+		if _, err = tx.ExecContext(ctx, startTiming, name, time.Now().Unix()); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	if len(missing) > 0 {
+		tx.Rollback()
+		return &MissingError{missing}
 	}
-	id, err := s.nameToID(name)
+	return tx.Commit()
+}
+
+const startTiming = `
+INSERT INTO timing (name, started) VALUES (?, ?)
+    ON CONFLICT (name) DO UPDATE SET started=excluded.started
+`
+
+const stopTiming = `
+UPDATE timing SET duration=?-started, started=NULL
+    WHERE name=? AND started IS NOT NULL
+`
+
+// ClaimToDo records that the builds of queued packages have begun
+func (s *SqliteStore) ClaimToDo(names ...string) error {
+	return s.ClaimToDoContext(context.Background(), names...)
+}
+
+// ClaimToDoContext records that the builds of queued packages have begun
+func (s *SqliteStore) ClaimToDoContext(ctx context.Context, names ...string) error {
+	if err := s.writable(); err != nil {
+		return err
+	}
+	tx, err := s.db.BeginTxx(ctx, nil)
 	if err != nil {
 		return err
 	}
-	_, err = s.db.Exec(insertToDo, name, id)
-	return err
+	now := time.Now().Unix()
+	for _, name := range names {
+		var count int
+		if err = tx.GetContext(ctx, &count, getToDo, name); err != nil {
+			tx.Rollback()
+			return err
+		}
+		if count == 0 {
+			tx.Rollback()
+			return &PackageError{name, ErrNotInToDo}
+		}
+		if _, err = tx.ExecContext(ctx, startTiming, name, now); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	return tx.Commit()
 }
 
-const checkDone = "SELECT done FROM todo WHERE name=? AND done=false"
+const checkDone = "SELECT done FROM todo WHERE name=? ORDER BY done LIMIT 1"
 const markDone = "UPDATE todo SET done=TRUE WHERE name=?"
 
 const insertReverse = `
 INSERT INTO todo
-    SELECT name, id, FALSE FROM packages INNER JOIN (
+    SELECT DISTINCT name, id, FALSE FROM packages INNER JOIN (
         SELECT left_id FROM deps WHERE right_id=?
     ) ON packages.id=left_id
     WHERE id NOT IN (SELECT package_id FROM todo)
 `
 
-// DoneToDo marks a package as complete and optionally queues its reverse deps
-func (s *SqliteStore) DoneToDo(name string, Continue bool) error {
+// DoneToDo marks packages as complete and optionally queues their reverse deps, all or nothing
+func (s *SqliteStore) DoneToDo(Continue bool, names ...string) error {
+	return s.DoneToDoContext(context.Background(), Continue, names...)
+}
+
+// DoneToDoContext marks packages as complete and optionally queues their reverse deps, all or nothing
+func (s *SqliteStore) DoneToDoContext(ctx context.Context, Continue bool, names ...string) error {
+	if err := s.writable(); err != nil {
+		return err
+	}
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	for _, name := range names {
+		if err = doneToDo(ctx, tx, name, Continue); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func doneToDo(ctx context.Context, tx *sqlx.Tx, name string, Continue bool) error {
 	done := false
-	err := s.db.Get(&done, checkDone, name)
+	err := tx.GetContext(ctx, &done, checkDone, name)
 	if err == sql.ErrNoRows {
-		return fmt.Errorf("Package '%s' is not in the todo list", name)
+		return &PackageError{name, ErrNotInToDo}
 	}
 	if err != nil {
 		return err
 	}
 	if done {
-		return fmt.Errorf("Package '%s' is already marked 'Done'", name)
+		return &PackageError{name, ErrAlreadyDone}
+	}
+	if _, err = tx.ExecContext(ctx, markDone, name); err != nil {
+		return err
 	}
-	if _, err = s.db.Exec(markDone, name); err != nil {
+	if _, err = tx.ExecContext(ctx, stopTiming, time.Now().Unix(), name); err != nil {
 		return err
 	}
 	if Continue {
-		id, err := s.nameToID(name)
-		if _, err = s.db.Exec(insertReverse, id); err != nil {
+		id, err := nameToID(ctx, tx, name)
+		if err != nil {
+			return err
+		}
+		if _, err = tx.ExecContext(ctx, insertReverse, id); err != nil {
 			return err
 		}
 	}
-	return err
+	return nil
+}
+
+// Rebuilt records a new release number for an existing package and, if it is pending in the todo list,
+// marks it as complete and queues its reverse deps, all or nothing
+func (s *SqliteStore) Rebuilt(name string, release int) (bool, error) {
+	return s.RebuiltContext(context.Background(), name, release)
+}
+
+// RebuiltContext records a new release number for an existing package and, if it is pending in the todo list,
+// marks it as complete and queues its reverse deps, all or nothing
+func (s *SqliteStore) RebuiltContext(ctx context.Context, name string, release int) (bool, error) {
+	if err := s.writable(); err != nil {
+		return false, err
+	}
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	result, err := tx.ExecContext(ctx, setRelease, release, name)
+	if err != nil {
+		tx.Rollback()
+		return false, err
+	}
+	count, err := result.RowsAffected()
+	if err != nil {
+		tx.Rollback()
+		return false, err
+	}
+	if count == 0 {
+		tx.Rollback()
+		return false, &PackageError{name, ErrPackageNotFound}
+	}
+	done := false
+	err = tx.GetContext(ctx, &done, checkDone, name)
+	pending := err == nil && !done
+	if err != nil && err != sql.ErrNoRows {
+		tx.Rollback()
+		return false, err
+	}
+	if pending {
+		if err = doneToDo(ctx, tx, name, true); err != nil {
+			tx.Rollback()
+			return false, err
+		}
+	}
+	return pending, tx.Commit()
 }
 
 const getUnblocked = `
-SELECT name FROM todo WHERE package_id
-NOT IN (
-    SELECT left_id FROM deps INNER JOIN (
-        SELECT package_id FROM todo WHERE done=FALSE
-    ) ON right_id=package_id
-) AND done=FALSE;
+SELECT todo.name AS name, todo.package_id AS id, component FROM todo
+    LEFT JOIN packages ON packages.id=todo.package_id
+WHERE todo.done=FALSE AND NOT EXISTS (
+    SELECT 1 FROM deps INNER JOIN todo AS blocker
+        ON blocker.package_id=deps.right_id AND blocker.done=FALSE
+    WHERE deps.left_id=todo.package_id
+);
+`
+const getImpact = `
+WITH RECURSIVE revs(id) AS (
+    SELECT left_id FROM deps WHERE right_id=?
+    UNION SELECT deps.left_id FROM revs INNER JOIN deps ON deps.right_id=revs.id
+)
+SELECT count(*) FROM revs INNER JOIN todo ON todo.package_id=revs.id AND todo.done=FALSE WHERE revs.id<>?
 `
 const getToDoCount = `SELECT count(*) FROM todo WHERE done=FALSE`
 const getToDoDone = `SELECT count(*) FROM todo WHERE done=TRUE`
 
 // GetToDo gets the currently unblocked packages to rebuild
 func (s *SqliteStore) GetToDo() (Packages, int, int, error) {
+	return s.GetToDoContext(context.Background())
+}
+
+// GetToDoContext gets the currently unblocked packages to rebuild
+func (s *SqliteStore) GetToDoContext(ctx context.Context) (Packages, int, int, error) {
 	unblocked := make(Packages, 0)
-	rows, err := s.db.Queryx(getUnblocked)
+	rows, err := s.db.QueryxContext(ctx, getUnblocked)
 	if err != nil {
 		return unblocked, 0, 0, err
 	}
+	var ids []int
 	for rows.Next() {
 		var name string
-		if err := rows.Scan(&name); err != nil {
+		var id int
+		var component sql.NullString
+		if err := rows.Scan(&name, &id, &component); err != nil {
 			return unblocked, 0, 0, err
 		}
-		unblocked = append(unblocked, Package{name, 0})
+		unblocked = append(unblocked, Package{Name: name, Component: component.String})
+		ids = append(ids, id)
+	}
+	if err = rows.Err(); err != nil {
+		return unblocked, 0, 0, err
+	}
+	enabled, err := closureEnabled(ctx, s.db)
+	if err != nil {
+		return unblocked, 0, 0, err
+	}
+	if enabled {
+		impacts, err := closureImpacts(ctx, s.db)
+		if err != nil {
+			return unblocked, 0, 0, err
+		}
+		for n := range unblocked {
+			unblocked[n].Impact = impacts[unblocked[n].Name]
+		}
+	} else {
+		for n, id := range ids {
+			if err = s.db.GetContext(ctx, &unblocked[n].Impact, getImpact, id, id); err != nil {
+				return unblocked, 0, 0, err
+			}
+		}
 	}
 	var count int
-	if err = s.db.Get(&count, getToDoCount); err != nil {
+	if err = s.db.GetContext(ctx, &count, getToDoCount); err != nil {
 		return unblocked, 0, 0, err
 	}
 	var done int
-	if err = s.db.Get(&done, getToDoDone); err != nil {
+	if err = s.db.GetContext(ctx, &done, getToDoDone); err != nil {
 		return unblocked, 0, 0, err
 	}
 	return unblocked, count, done, err
 }
 
-const getWorst = `
-WITH RECURSIVE traverse AS (
-    SELECT left_id FROM deps INNER JOIN packages
-    ON right_id=id WHERE name=?
-    UNION ALL
-    SELECT deps.left_id FROM deps
-        INNER JOIN traverse
-        ON deps.right_id=traverse.left_id
-)
-SELECT name FROM traverse INNER JOIN packages
-ON id=left_id GROUP BY name;
+// worst holds each package reached so far by WorstToDo, once, at the depth it was first reached
+const createWorst = `
+CREATE TEMP TABLE IF NOT EXISTS worst (
+    pkg   INTEGER PRIMARY KEY,
+    depth INTEGER
+);
+CREATE INDEX IF NOT EXISTS temp.worst_depth ON worst (depth);
+DELETE FROM temp.worst;
+`
+
+const insertWorstFirst = `
+INSERT OR IGNORE INTO temp.worst
+    SELECT left_id, 1 FROM deps
+    WHERE right_id=? AND left_id NOT IN (SELECT id FROM packages WHERE name IN (%s))
 `
 
-// WorstToDo gets a worst-case list of packages to rebuild
-func (s *SqliteStore) WorstToDo(name string) (Packages, error) {
+const insertWorstNext = `
+INSERT OR IGNORE INTO temp.worst
+    SELECT DISTINCT deps.left_id, ? FROM temp.worst INNER JOIN deps ON deps.right_id=worst.pkg
+    WHERE worst.depth=? AND deps.left_id NOT IN (SELECT id FROM packages WHERE name IN (%s))
+`
+
+const getWorst = "SELECT name, component, depth FROM temp.worst INNER JOIN packages ON id=pkg"
+
+// WorstToDo gets a worst-case list of packages to rebuild and the minimum depth each is reached at,
+// going no deeper than maxDepth (unless zero) and not rebuilding or traversing past excluded packages
+func (s *SqliteStore) WorstToDo(name string, maxDepth int, exclude ...string) (Packages, error) {
+	return s.WorstToDoContext(context.Background(), name, maxDepth, exclude...)
+}
+
+// WorstToDoContext gets a worst-case list of packages to rebuild and the minimum depth each is reached at,
+// going no deeper than maxDepth (unless zero) and not rebuilding or traversing past excluded packages
+func (s *SqliteStore) WorstToDoContext(ctx context.Context, name string, maxDepth int, exclude ...string) (Packages, error) {
 	list := make(Packages, 0)
-	rows, err := s.db.Queryx(getWorst, name)
+	id, err := nameToID(ctx, s.db, name)
+	if err != nil {
+		return list, err
+	}
+	// Paths through excluded packages still count towards the closure, so it can only be used without them
+	if len(exclude) == 0 {
+		enabled, err := closureEnabled(ctx, s.db)
+		if err != nil {
+			return list, err
+		}
+		if enabled {
+			err = s.db.SelectContext(ctx, &list, getClosure, id, maxDepth, maxDepth)
+			return list, err
+		}
+	}
+	placeholders := "NULL"
+	excluded := make([]interface{}, 0, len(exclude))
+	if len(exclude) > 0 {
+		placeholders = strings.Repeat(",?", len(exclude))[1:]
+		for _, name := range exclude {
+			excluded = append(excluded, name)
+		}
+	}
+	// Temporary tables only exist for a single connection
+	conn, err := s.db.Connx(ctx)
+	if err != nil {
+		return list, err
+	}
+	defer conn.Close()
+	if _, err = conn.ExecContext(ctx, createWorst); err != nil {
+		return list, err
+	}
+	// Breadth-first, one level at a time, so each package is only visited once
+	result, err := conn.ExecContext(ctx, fmt.Sprintf(insertWorstFirst, placeholders), append([]interface{}{id}, excluded...)...)
+	for depth := 2; maxDepth <= 0 || depth <= maxDepth; depth++ {
+		if err != nil {
+			return list, err
+		}
+		var count int64
+		if count, err = result.RowsAffected(); err != nil || count == 0 {
+			break
+		}
+		args := append([]interface{}{depth, depth - 1}, excluded...)
+		result, err = conn.ExecContext(ctx, fmt.Sprintf(insertWorstNext, placeholders), args...)
+	}
+	if err != nil {
+		return list, err
+	}
+	rows, err := conn.QueryxContext(ctx, getWorst)
 	if err != nil {
 		return list, err
 	}
 	for rows.Next() {
-		var pName string
-		if err = rows.Scan(&pName); err != nil {
+		var p Package
+		if err = rows.StructScan(&p); err != nil {
 			return list, err
 		}
-		list = append(list, Package{pName, 0})
+		list = append(list, p)
 	}
-	return list, err
+	return list, rows.Err()
 }
 
-const resetToDo = "DELETE FROM todo"
+const getPending = "SELECT name FROM todo WHERE done=FALSE"
+
+const getPendingDeps = `
+SELECT l.name AS left_name, r.name AS right_name, deps.rel AS rel FROM deps
+    INNER JOIN todo AS l ON l.package_id=left_id AND l.done=FALSE
+    INNER JOIN todo AS r ON r.package_id=right_id AND r.done=FALSE
+`
+
+// GetPending returns every queued package and the dependencies between them
+func (s *SqliteStore) GetPending() (Packages, Dependencies, error) {
+	return s.GetPendingContext(context.Background())
+}
+
+// GetPendingContext returns every queued package and the dependencies between them
+func (s *SqliteStore) GetPendingContext(ctx context.Context) (Packages, Dependencies, error) {
+	pkgs := make(Packages, 0)
+	if err := s.db.SelectContext(ctx, &pkgs, getPending); err != nil {
+		return pkgs, nil, err
+	}
+	deps := make(Dependencies, 0)
+	err := s.db.SelectContext(ctx, &deps, getPendingDeps)
+	return pkgs, deps, err
+}
+
+const getDurations = "SELECT name, duration FROM timing WHERE duration IS NOT NULL"
+
+// GetDurations returns every package with a recorded build duration
+func (s *SqliteStore) GetDurations() (Packages, error) {
+	return s.GetDurationsContext(context.Background())
+}
+
+// GetDurationsContext returns every package with a recorded build duration
+func (s *SqliteStore) GetDurationsContext(ctx context.Context) (Packages, error) {
+	pkgs := make(Packages, 0)
+	err := s.db.SelectContext(ctx, &pkgs, getDurations)
+	return pkgs, err
+}
+
+const setDuration = `
+INSERT INTO timing (name, duration) VALUES (?, ?)
+    ON CONFLICT (name) DO UPDATE SET duration=excluded.duration
+`
+
+// SetDurations records the build duration of each package, replacing any existing records
+func (s *SqliteStore) SetDurations(pkgs Packages) error {
+	return s.SetDurationsContext(context.Background(), pkgs)
+}
+
+// SetDurationsContext records the build duration of each package, replacing any existing records
+func (s *SqliteStore) SetDurationsContext(ctx context.Context, pkgs Packages) error {
+	if err := s.writable(); err != nil {
+		return err
+	}
+	if err := checkDurations(pkgs); err != nil {
+		return err
+	}
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	for _, pkg := range pkgs {
+		if _, err = tx.ExecContext(ctx, setDuration, pkg.Name, pkg.Duration); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+const resetToDo = `
+DELETE FROM todo;
+UPDATE timing SET started=NULL;
+`
 
 // ResetToDo clears the todo list
 func (s *SqliteStore) ResetToDo() error {
-	_, err := s.db.Exec(resetToDo)
-	return err
+	return s.ResetToDoContext(context.Background())
+}
+
+// ResetToDoContext clears the todo list
+func (s *SqliteStore) ResetToDoContext(ctx context.Context) error {
+	if err := s.writable(); err != nil {
+		return err
+	}
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if _, err = tx.ExecContext(ctx, resetToDo); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
 }
 
-const dropTables = `
+const dropIndexTables = `
     DROP TABLE IF EXISTS packages;
     DROP TABLE IF EXISTS deps;
+    DROP TABLE IF EXISTS provides;
+    DROP TABLE IF EXISTS closure;
+`
+
+const dropTables = dropIndexTables + `
     DROP TABLE IF EXISTS todo;
 `
 
-const insertPackage = "INSERT INTO packages VALUES (?,?,?)"
+const insertPackage = "INSERT INTO packages VALUES (?,?,?,?,?)"
 const insertDep = "INSERT INTO deps VALUES (?,?,?)"
+const insertProvide = "INSERT INTO provides VALUES (?,?,?)"
 
-// Update rebuilds the store from an Index
-func (s *SqliteStore) Update(i *index.Index) error {
-	s.db.MustExec(dropTables)
-	s.createTables()
-	tx := s.db.MustBegin()
-	pkgStmt, err := tx.Preparex(insertPackage)
+// Update rebuilds the store from an index, walking it only once
+func (s *SqliteStore) Update(i index.Walker) error {
+	return s.UpdateContext(context.Background(), i)
+}
+
+// UpdateContext rebuilds the store from an index, walking it only once
+func (s *SqliteStore) UpdateContext(ctx context.Context, i index.Walker) error {
+	if err := s.writable(); err != nil {
+		return err
+	}
+	// Replace the tables inside the transaction, so a cancelled Update leaves the old ones in place
+	tx, err := s.db.BeginTxx(ctx, nil)
 	if err != nil {
 		return err
 	}
+	if err = keepClosure(ctx, tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	if _, err = tx.ExecContext(ctx, dropTables); err != nil {
+		tx.Rollback()
+		return err
+	}
+	if _, err = tx.ExecContext(ctx, schema); err != nil {
+		tx.Rollback()
+		return err
+	}
+	pkgStmt, err := tx.PreparexContext(ctx, insertPackage)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
 	// Get ID mappings
 	idMap := make(map[string]int)
-	for id, pkg := range i.Packages {
-		// skip -devel and -dbginfo packages
-		if strings.HasSuffix(pkg.Name, "-dbginfo") || strings.HasSuffix(pkg.Name, "-devel") {
-			continue
-		}
+	deps, provides, err := walkImportable(ctx, i, func(pkg Package) error {
+		id := len(idMap)
 		idMap[pkg.Name] = id
-		if _, err = pkgStmt.Exec(id, pkg.Name, pkg.Releases[0].Number); err != nil {
+		_, err := pkgStmt.ExecContext(ctx, id, pkg.Name, pkg.Release, pkg.Summary, pkg.Component)
+		return err
+	})
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	provideStmt, err := tx.PreparexContext(ctx, insertProvide)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	for _, provide := range provides {
+		if _, err = provideStmt.ExecContext(ctx, idMap[provide.Package], provide.Kind, provide.Name); err != nil {
 			tx.Rollback()
 			return err
 		}
 	}
 
-	depStmt, err := tx.Preparex(insertDep)
+	depStmt, err := tx.PreparexContext(ctx, insertDep)
 	if err != nil {
 		tx.Rollback()
 		return err
 	}
-	//Get left-right mappings
-	for leftID, lpkg := range i.Packages {
-		for _, rpkg := range lpkg.RuntimeDependencies {
-			rightID := idMap[rpkg.Name]
-			if _, err = depStmt.Exec(leftID, rightID, rpkg.Release); err != nil {
-				tx.Rollback()
-				return err
-			}
+	for _, dep := range deps {
+		if _, err = depStmt.ExecContext(ctx, idMap[dep.Left], idMap[dep.Right], dep.Release); err != nil {
+			tx.Rollback()
+			return err
 		}
 	}
 
+	// Building the indexes once is faster than keeping them up to date through every insert
+	if _, err = tx.ExecContext(ctx, indexes); err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err = refreshClosure(ctx, tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err = forgetStamp(ctx, tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+const dumpProvides = `
+SELECT packages.name AS package, kind, provides.name AS name FROM provides
+    INNER JOIN packages ON packages.id=package_id
+`
+const dumpToDo = "SELECT name, done FROM todo"
+const dumpTiming = "SELECT name, started, duration FROM timing"
+
+// Dump gets the entire contents of the store
+func (s *SqliteStore) Dump() (*Dump, error) {
+	return s.DumpContext(context.Background())
+}
+
+// DumpContext gets the entire contents of the store
+func (s *SqliteStore) DumpContext(ctx context.Context) (*Dump, error) {
+	// Read everything in one transaction, so the dump is consistent
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+	d := &Dump{
+		Version:      DumpVersion,
+		Packages:     make(Packages, 0),
+		Dependencies: make(Dependencies, 0),
+		Provides:     make(Provides, 0),
+		ToDo:         make([]ToDo, 0),
+		Timing:       make([]Timing, 0),
+	}
+	if err = tx.SelectContext(ctx, &d.Packages, getPackages); err != nil {
+		return nil, err
+	}
+	if err = tx.SelectContext(ctx, &d.Dependencies, getDeps); err != nil {
+		return nil, err
+	}
+	if err = tx.SelectContext(ctx, &d.Provides, dumpProvides); err != nil {
+		return nil, err
+	}
+	if err = tx.SelectContext(ctx, &d.ToDo, dumpToDo); err != nil {
+		return nil, err
+	}
+	if err = tx.SelectContext(ctx, &d.Timing, dumpTiming); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+const clearTiming = "DELETE FROM timing"
+const insertToDoItem = "INSERT INTO todo VALUES (?, ?, ?)"
+const insertTiming = "INSERT INTO timing VALUES (?, ?, ?)"
+
+// Restore replaces the entire contents of the store with a dump, all or nothing
+func (s *SqliteStore) Restore(d *Dump) error {
+	return s.RestoreContext(context.Background(), d)
+}
+
+// RestoreContext replaces the entire contents of the store with a dump, all or nothing
+func (s *SqliteStore) RestoreContext(ctx context.Context, d *Dump) error {
+	if err := s.writable(); err != nil {
+		return err
+	}
+	if err := d.Check(); err != nil {
+		return err
+	}
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if err = restore(ctx, tx, d); err != nil {
+		tx.Rollback()
+		return err
+	}
 	return tx.Commit()
 }
 
+func restore(ctx context.Context, tx *sqlx.Tx, d *Dump) error {
+	if err := keepClosure(ctx, tx); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, dropTables); err != nil {
+		return err
+	}
+	if err := createTables(ctx, tx); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, clearTiming); err != nil {
+		return err
+	}
+	ids := make(map[string]int, len(d.Packages))
+	for id, pkg := range d.Packages {
+		ids[pkg.Name] = id
+		if _, err := tx.ExecContext(ctx, insertPackage, id, pkg.Name, pkg.Release, pkg.Summary, pkg.Component); err != nil {
+			return err
+		}
+	}
+	for _, dep := range d.Dependencies {
+		if _, err := tx.ExecContext(ctx, insertDep, ids[dep.Left], ids[dep.Right], dep.Release); err != nil {
+			return err
+		}
+	}
+	for _, provide := range d.Provides {
+		if _, err := tx.ExecContext(ctx, insertProvide, ids[provide.Package], provide.Kind, provide.Name); err != nil {
+			return err
+		}
+	}
+	for _, item := range d.ToDo {
+		if _, err := tx.ExecContext(ctx, insertToDoItem, item.Name, ids[item.Name], item.Done); err != nil {
+			return err
+		}
+	}
+	for _, record := range d.Timing {
+		if _, err := tx.ExecContext(ctx, insertTiming, record.Name, record.Started, record.Duration); err != nil {
+			return err
+		}
+	}
+	if err := refreshClosure(ctx, tx); err != nil {
+		return err
+	}
+	return forgetStamp(ctx, tx)
+}
+
 // Close deinitializes the connection to the backend store
 func (s *SqliteStore) Close() error {
 	if !s.open {
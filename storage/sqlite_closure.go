@@ -0,0 +1,254 @@
+//
+// Copyright 2018-2021 Bryan T. Meyers <root@datadrake.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package storage
+
+import (
+	"context"
+	"github.com/jmoiron/sqlx"
+)
+
+const closureKey = "closure"
+
+const getClosureEnabled = "SELECT count(*) FROM metadata WHERE key=?"
+const enableClosure = "INSERT OR REPLACE INTO metadata VALUES (?, '')"
+const disableClosure = "DELETE FROM metadata WHERE key=?"
+
+// closureEnabled checks if the closure table is being kept up to date
+func closureEnabled(ctx context.Context, q sqlx.QueryerContext) (bool, error) {
+	var count int
+	err := sqlx.GetContext(ctx, q, &count, getClosureEnabled, closureKey)
+	return count > 0, err
+}
+
+const getClosure = `
+SELECT name, component, depth FROM closure INNER JOIN packages ON id=left_id
+    WHERE right_id=? AND (?<=0 OR depth<=?)
+`
+
+const getClosureDeps = `
+SELECT DISTINCT left_id, right_id FROM deps
+    INNER JOIN packages AS l ON l.id=left_id
+    INNER JOIN packages AS r ON r.id=right_id
+`
+const clearClosure = "DELETE FROM closure"
+const insertClosure = "INSERT INTO closure VALUES (?,?,?)"
+
+// buildClosure fills the closure table with every package which depends on each package in
+// stale, or on every package if stale is nil, directly or not, at the minimum depth it is reached at
+func buildClosure(ctx context.Context, tx *sqlx.Tx, stale map[int]bool) error {
+	if stale == nil {
+		if _, err := tx.ExecContext(ctx, clearClosure); err != nil {
+			return err
+		}
+	}
+	rows, err := tx.QueryxContext(ctx, getClosureDeps)
+	if err != nil {
+		return err
+	}
+	revs := make(map[int][]int)
+	var roots []int
+	for rows.Next() {
+		var left, right int
+		if err = rows.Scan(&left, &right); err != nil {
+			rows.Close()
+			return err
+		}
+		if _, ok := revs[right]; !ok && (stale == nil || stale[right]) {
+			roots = append(roots, right)
+		}
+		revs[right] = append(revs[right], left)
+	}
+	if err = rows.Err(); err != nil {
+		return err
+	}
+	stmt, err := tx.PreparexContext(ctx, insertClosure)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+	for _, root := range roots {
+		// Breadth-first, so each package is first reached at its minimum depth
+		depths := make(map[int]int)
+		level := []int{root}
+		for depth := 1; len(level) > 0; depth++ {
+			var next []int
+			for _, right := range level {
+				for _, left := range revs[right] {
+					if _, seen := depths[left]; seen {
+						continue
+					}
+					depths[left] = depth
+					next = append(next, left)
+					if _, err = stmt.ExecContext(ctx, root, left, depth); err != nil {
+						return err
+					}
+				}
+			}
+			level = next
+		}
+	}
+	return nil
+}
+
+// The ids of packages change with every Update or Restore, so the closure is kept by name
+const keepOldClosure = `
+DROP TABLE IF EXISTS temp.old_closure;
+DROP TABLE IF EXISTS temp.old_deps;
+CREATE TEMP TABLE old_closure AS
+    SELECT r.name AS right_name, l.name AS left_name, depth FROM closure
+    INNER JOIN packages AS r ON r.id=right_id
+    INNER JOIN packages AS l ON l.id=left_id;
+CREATE TEMP TABLE old_deps AS
+    SELECT DISTINCT l.name AS left_name, r.name AS right_name FROM deps
+    INNER JOIN packages AS l ON l.id=left_id
+    INNER JOIN packages AS r ON r.id=right_id;
+`
+
+// Only the closures which contained, or were of, the right side of an added or removed dependency
+// can change. Any new path starts with unchanged edges up to its first added one, so checking the
+// old closures is enough.
+const findStaleClosure = `
+DROP TABLE IF EXISTS temp.stale;
+CREATE TEMP TABLE stale AS
+    WITH new_deps AS (
+        SELECT DISTINCT l.name AS left_name, r.name AS right_name FROM deps
+        INNER JOIN packages AS l ON l.id=left_id
+        INNER JOIN packages AS r ON r.id=right_id
+    ), changed AS (
+        SELECT right_name FROM (SELECT * FROM temp.old_deps EXCEPT SELECT * FROM new_deps)
+        UNION SELECT right_name FROM (SELECT * FROM new_deps EXCEPT SELECT * FROM temp.old_deps)
+    )
+    SELECT right_name AS name FROM changed
+    UNION SELECT old_closure.right_name FROM temp.old_closure
+        INNER JOIN changed ON old_closure.left_name=changed.right_name;
+`
+
+const copyOldClosure = `
+INSERT INTO closure
+    SELECT r.id, l.id, depth FROM temp.old_closure
+    INNER JOIN packages AS r ON r.name=right_name
+    INNER JOIN packages AS l ON l.name=left_name
+    WHERE right_name NOT IN (SELECT name FROM temp.stale)
+`
+
+const getStaleClosure = "SELECT id FROM packages WHERE name IN (SELECT name FROM temp.stale)"
+
+const dropOldClosure = `
+DROP TABLE temp.old_closure;
+DROP TABLE temp.old_deps;
+DROP TABLE temp.stale;
+`
+
+// keepClosure copies the closure table aside before the packages are replaced, if it is being kept up to date
+func keepClosure(ctx context.Context, tx *sqlx.Tx) error {
+	enabled, err := closureEnabled(ctx, tx)
+	if err != nil || !enabled {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, keepOldClosure)
+	return err
+}
+
+// refreshClosure brings the closure table kept by keepClosure up to date with the new packages,
+// only traversing again from packages whose closure could have changed
+func refreshClosure(ctx context.Context, tx *sqlx.Tx) error {
+	enabled, err := closureEnabled(ctx, tx)
+	if err != nil || !enabled {
+		return err
+	}
+	if _, err = tx.ExecContext(ctx, findStaleClosure); err != nil {
+		return err
+	}
+	if _, err = tx.ExecContext(ctx, copyOldClosure); err != nil {
+		return err
+	}
+	var ids []int
+	if err = tx.SelectContext(ctx, &ids, getStaleClosure); err != nil {
+		return err
+	}
+	stale := make(map[int]bool, len(ids))
+	for _, id := range ids {
+		stale[id] = true
+	}
+	if err = buildClosure(ctx, tx, stale); err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, dropOldClosure)
+	return err
+}
+
+const getClosureImpacts = `
+SELECT r.name AS name, count(*) AS impact FROM todo AS r
+    INNER JOIN closure ON closure.right_id=r.package_id
+    INNER JOIN todo AS l ON l.package_id=closure.left_id AND l.done=FALSE
+    WHERE r.done=FALSE AND closure.left_id<>closure.right_id
+    GROUP BY r.name
+`
+
+// closureImpacts counts the pending packages which depend on each pending package, directly or not
+func closureImpacts(ctx context.Context, q sqlx.QueryerContext) (map[string]int, error) {
+	var pkgs Packages
+	if err := sqlx.SelectContext(ctx, q, &pkgs, getClosureImpacts); err != nil {
+		return nil, err
+	}
+	impacts := make(map[string]int, len(pkgs))
+	for _, pkg := range pkgs {
+		impacts[pkg.Name] = pkg.Impact
+	}
+	return impacts, nil
+}
+
+// SetClosure turns on or off keeping a table of the reverse closure of every package,
+// building it straight away if turned on
+func (s *SqliteStore) SetClosure(enabled bool) error {
+	return s.SetClosureContext(context.Background(), enabled)
+}
+
+// SetClosureContext turns on or off keeping a table of the reverse closure of every package,
+// building it straight away if turned on
+func (s *SqliteStore) SetClosureContext(ctx context.Context, enabled bool) error {
+	if err := s.writable(); err != nil {
+		return err
+	}
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if err = setClosure(ctx, tx, enabled); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+func setClosure(ctx context.Context, tx *sqlx.Tx, enabled bool) error {
+	already, err := closureEnabled(ctx, tx)
+	if err != nil || already == enabled {
+		return err
+	}
+	if !enabled {
+		if _, err = tx.ExecContext(ctx, disableClosure, closureKey); err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(ctx, clearClosure)
+		return err
+	}
+	if _, err = tx.ExecContext(ctx, enableClosure, closureKey); err != nil {
+		return err
+	}
+	return buildClosure(ctx, tx, nil)
+}
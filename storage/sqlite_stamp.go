@@ -0,0 +1,104 @@
+//
+// Copyright 2018-2021 Bryan T. Meyers <root@datadrake.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package storage
+
+import (
+	"context"
+	"github.com/DataDrake/eopkg-deps/index"
+	"github.com/jmoiron/sqlx"
+	"time"
+)
+
+// Metadata keys for the Stamp of the index last updated from
+const (
+	stampPathKey    = "index_path"
+	stampModTimeKey = "index_mtime"
+	stampHashKey    = "index_hash"
+)
+
+const getMetadata = "SELECT key, value FROM metadata WHERE key IN (?, ?, ?)"
+const setMetadata = "INSERT OR REPLACE INTO metadata VALUES (?, ?)"
+const clearStamp = "DELETE FROM metadata WHERE key IN (?, ?, ?)"
+
+// GetStamp gets which index file the store was last updated from, or a zero Stamp if unknown
+func (s *SqliteStore) GetStamp() (index.Stamp, error) {
+	return s.GetStampContext(context.Background())
+}
+
+// GetStampContext gets which index file the store was last updated from, or a zero Stamp if unknown
+func (s *SqliteStore) GetStampContext(ctx context.Context) (stamp index.Stamp, err error) {
+	rows, err := s.db.QueryxContext(ctx, getMetadata, stampPathKey, stampModTimeKey, stampHashKey)
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+	values := make(map[string]string)
+	for rows.Next() {
+		var key, value string
+		if err = rows.Scan(&key, &value); err != nil {
+			return
+		}
+		values[key] = value
+	}
+	if err = rows.Err(); err != nil || len(values) != 3 {
+		return
+	}
+	modTime, err := time.Parse(time.RFC3339Nano, values[stampModTimeKey])
+	if err != nil {
+		return
+	}
+	stamp = index.Stamp{
+		Path:    values[stampPathKey],
+		ModTime: modTime,
+		Hash:    values[stampHashKey],
+	}
+	return
+}
+
+// SetStamp records which index file the store was last updated from, until the next Update or Restore
+func (s *SqliteStore) SetStamp(stamp index.Stamp) error {
+	return s.SetStampContext(context.Background(), stamp)
+}
+
+// SetStampContext records which index file the store was last updated from, until the next Update or Restore
+func (s *SqliteStore) SetStampContext(ctx context.Context, stamp index.Stamp) error {
+	if err := s.writable(); err != nil {
+		return err
+	}
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	values := map[string]string{
+		stampPathKey:    stamp.Path,
+		stampModTimeKey: stamp.ModTime.Format(time.RFC3339Nano),
+		stampHashKey:    stamp.Hash,
+	}
+	for key, value := range values {
+		if _, err = tx.ExecContext(ctx, setMetadata, key, value); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// forgetStamp clears the Stamp once the store no longer matches the index it was updated from
+func forgetStamp(ctx context.Context, e sqlx.ExecerContext) error {
+	_, err := e.ExecContext(ctx, clearStamp, stampPathKey, stampModTimeKey, stampHashKey)
+	return err
+}
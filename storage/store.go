@@ -17,35 +17,218 @@
 package storage
 
 import (
+	"context"
 	"github.com/DataDrake/eopkg-deps/index"
+	"strings"
 )
 
-// Store is a common interface for all kinds of backing store
+// Store is a common interface for all kinds of backing store.
+// Failures caused by the packages involved are reported with the errors in errors.go.
+// Every method without a context runs as if given context.Background().
 type Store interface {
+	ContextStore
 	// Open initializes a connection to the backend store
 	Open(location string) error
-	// GetForward returns: (left) -> *
+	// OpenReadOnly initializes a connection to the backend store, where every change fails with ErrReadOnly
+	OpenReadOnly(location string) error
+	// GetForward returns: (left) -> *, with the release each dependency applies from and the
+	// current release of each package
 	GetForward(lhs string) (Packages, error)
-	// GetReverse returns: * -> (right)
+	// GetReverse returns: * -> (right), with the release each dependency applies from and the
+	// current release of each package
 	GetReverse(rhs string) (Packages, error)
-	// GetToDo returns a list of unblocked packages that need to be rebuilt,
-	// the count of remaining rebuilds, and the count of completed rebuilds
+	// GetProvides returns the names provided by a package
+	GetProvides(name string) (Provides, error)
+	// WhatProvides returns the packages which provide a name, failing with ErrNotProvided if there are none
+	WhatProvides(name string) (Packages, error)
+	// GetGraph returns every package and every dependency between them
+	GetGraph() (Packages, Dependencies, error)
+	// GetPackages returns every package
+	GetPackages() (Packages, error)
+	// GetPackage returns a single package by name
+	GetPackage(name string) (Package, error)
+	// SetRelease records a new release number for an existing package
+	SetRelease(name string, release int) error
+	// Rebuilt records a new release number for an existing package and, if it is pending in the todo list,
+	// marks it as complete and queues its reverse deps, all or nothing, reporting if it was pending
+	Rebuilt(name string, release int) (bool, error)
+	// GetToDo returns a list of unblocked packages that need to be rebuilt, with the number of pending
+	// packages depending on each, the count of remaining rebuilds, and the count of completed rebuilds
 	GetToDo() (Packages, int, int, error)
-	// StartToDo adds a new package to the todo list
-	StartToDo(name string) error
-	// DoneToDo marks a package as complete and optionally queues its reverse deps
-	DoneToDo(name string, Continue bool) error
+	// StartToDo adds new packages to the todo list, all or nothing
+	StartToDo(names ...string) error
+	// DoneToDo marks packages as complete and optionally queues their reverse deps, all or nothing
+	DoneToDo(Continue bool, names ...string) error
+	// ClaimToDo records that the builds of queued packages have begun
+	ClaimToDo(names ...string) error
+	// GetPending returns every queued package and the dependencies between them
+	GetPending() (Packages, Dependencies, error)
+	// GetDurations returns every package with a recorded build duration
+	GetDurations() (Packages, error)
+	// SetDurations records the build duration of each package, replacing any existing records
+	SetDurations(pkgs Packages) error
 	// ResetToDo clears the todo list
 	ResetToDo() error
-	// WorstToDo gets a worst-case list of packages to rebuild
-	WorstToDo(name string) (Packages, error)
-	// Update clears the current store and rebuilds the contents from the provided index
-	Update(i *index.Index) error
+	// WorstToDo gets a worst-case list of packages to rebuild and the minimum depth each is reached at,
+	// going no deeper than maxDepth (unless zero) and not rebuilding or traversing past excluded packages
+	WorstToDo(name string, maxDepth int, exclude ...string) (Packages, error)
+	// Update clears the current store and rebuilds the contents from the provided index, walking it only once
+	Update(i index.Walker) error
+	// Dump gets the entire contents of the store
+	Dump() (*Dump, error)
+	// Restore replaces the entire contents of the store with a dump, all or nothing,
+	// failing with ErrInvalidDump if it does not pass Check
+	Restore(d *Dump) error
+	// SetClosure turns on or off keeping the reverse closure of every package, which speeds up
+	// WorstToDo without excluded packages and GetToDo at the cost of a slower Update and Restore,
+	// which only traverse again from packages whose reverse closure could have changed
+	SetClosure(enabled bool) error
+	// GetStamp gets which index file the store was last updated from, or a zero Stamp if unknown
+	GetStamp() (index.Stamp, error)
+	// SetStamp records which index file the store was last updated from, until the next Update or Restore
+	SetStamp(stamp index.Stamp) error
 	// Close deinitializes the connection to the backend store
 	Close() error
 }
 
+// ContextStore has a variant of each Store method which stops early, returning the error
+// from the context, once the context is cancelled or its deadline passes
+type ContextStore interface {
+	// OpenContext is Open with a context
+	OpenContext(ctx context.Context, location string) error
+	// OpenReadOnlyContext is OpenReadOnly with a context
+	OpenReadOnlyContext(ctx context.Context, location string) error
+	// GetForwardContext is GetForward with a context
+	GetForwardContext(ctx context.Context, lhs string) (Packages, error)
+	// GetReverseContext is GetReverse with a context
+	GetReverseContext(ctx context.Context, rhs string) (Packages, error)
+	// GetProvidesContext is GetProvides with a context
+	GetProvidesContext(ctx context.Context, name string) (Provides, error)
+	// WhatProvidesContext is WhatProvides with a context
+	WhatProvidesContext(ctx context.Context, name string) (Packages, error)
+	// GetGraphContext is GetGraph with a context
+	GetGraphContext(ctx context.Context) (Packages, Dependencies, error)
+	// GetPackagesContext is GetPackages with a context
+	GetPackagesContext(ctx context.Context) (Packages, error)
+	// GetPackageContext is GetPackage with a context
+	GetPackageContext(ctx context.Context, name string) (Package, error)
+	// SetReleaseContext is SetRelease with a context
+	SetReleaseContext(ctx context.Context, name string, release int) error
+	// RebuiltContext is Rebuilt with a context
+	RebuiltContext(ctx context.Context, name string, release int) (bool, error)
+	// GetToDoContext is GetToDo with a context
+	GetToDoContext(ctx context.Context) (Packages, int, int, error)
+	// StartToDoContext is StartToDo with a context
+	StartToDoContext(ctx context.Context, names ...string) error
+	// DoneToDoContext is DoneToDo with a context
+	DoneToDoContext(ctx context.Context, Continue bool, names ...string) error
+	// ClaimToDoContext is ClaimToDo with a context
+	ClaimToDoContext(ctx context.Context, names ...string) error
+	// GetPendingContext is GetPending with a context
+	GetPendingContext(ctx context.Context) (Packages, Dependencies, error)
+	// GetDurationsContext is GetDurations with a context
+	GetDurationsContext(ctx context.Context) (Packages, error)
+	// SetDurationsContext is SetDurations with a context
+	SetDurationsContext(ctx context.Context, pkgs Packages) error
+	// ResetToDoContext is ResetToDo with a context
+	ResetToDoContext(ctx context.Context) error
+	// WorstToDoContext is WorstToDo with a context
+	WorstToDoContext(ctx context.Context, name string, maxDepth int, exclude ...string) (Packages, error)
+	// UpdateContext is Update with a context, leaving the store as it was if cancelled
+	UpdateContext(ctx context.Context, i index.Walker) error
+	// DumpContext is Dump with a context
+	DumpContext(ctx context.Context) (*Dump, error)
+	// RestoreContext is Restore with a context, leaving the store as it was if cancelled
+	RestoreContext(ctx context.Context, d *Dump) error
+	// SetClosureContext is SetClosure with a context, leaving the store as it was if cancelled
+	SetClosureContext(ctx context.Context, enabled bool) error
+	// GetStampContext is GetStamp with a context
+	GetStampContext(ctx context.Context) (index.Stamp, error)
+	// SetStampContext is SetStamp with a context
+	SetStampContext(ctx context.Context, stamp index.Stamp) error
+}
+
 // NewStore gets a new version of the current preferred backing store
 func NewStore() Store {
 	return NewSqliteStore()
 }
+
+// skipped checks if a package from the index is left out of every store
+func skipped(name string) bool {
+	return strings.HasSuffix(name, "-dbginfo") || strings.HasSuffix(name, "-devel")
+}
+
+// walkImportable goes through an index once, calling add with every package which can go into a
+// store, then gets the dependencies and provides between those packages. Packages without a release
+// history and all but the first of any sharing a name are left out, which index.Lint reports as errors.
+func walkImportable(ctx context.Context, w index.Walker, add func(pkg Package) error) (Dependencies, Provides, error) {
+	seen := make(map[string]bool)
+	added := make(map[string]bool)
+	var deps Dependencies
+	var provides Provides
+	err := w.Walk(func(pkg *index.Package) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if len(pkg.Releases) == 0 || seen[pkg.Name] {
+			return nil
+		}
+		seen[pkg.Name] = true
+		// Anything else might point at a package further on, so can only be sorted out at the end
+		provider := providerOf(pkg.Name)
+		for _, name := range pkg.Provides.PkgConfig {
+			provides = append(provides, Provide{provider, ProvidesPkgConfig, name})
+		}
+		for _, name := range pkg.Provides.PkgConfig32 {
+			provides = append(provides, Provide{provider, ProvidesPkgConfig32, name})
+		}
+		// skip -devel and -dbginfo packages
+		if skipped(pkg.Name) {
+			return nil
+		}
+		added[pkg.Name] = true
+		for _, dep := range pkg.RuntimeDependencies {
+			deps = append(deps, Dependency{Left: pkg.Name, Right: dep.Name, Release: dep.Release})
+		}
+		return add(Package{
+			Name:      pkg.Name,
+			Release:   pkg.Releases[0].Number,
+			Summary:   pkg.Summary(),
+			Component: pkg.Component,
+		})
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	// Filter in place, since nothing else holds on to either slice
+	known := deps[:0]
+	for _, dep := range deps {
+		if added[dep.Right] {
+			known = append(known, dep)
+		}
+	}
+	provided := provides[:0]
+	for _, provide := range provides {
+		if added[provide.Package] {
+			provided = append(provided, provide)
+		}
+	}
+	return known, provided, nil
+}
+
+// providerOf gets the package which provides names on behalf of a package from the index,
+// since -devel packages carry the pkg-config files of the package they were split from
+func providerOf(name string) string {
+	return strings.TrimSuffix(name, "-devel")
+}
+
+// checkDurations rejects a batch of durations if any of them is negative, as a dump of the store
+// would then fail Dump.Check and could not be restored
+func checkDurations(pkgs Packages) error {
+	for _, pkg := range pkgs {
+		if pkg.Duration < 0 {
+			return &PackageError{Name: pkg.Name, Err: ErrNegativeDuration}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,199 @@
+//
+// Copyright 2018-2021 Bryan T. Meyers <root@datadrake.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package storetest
+
+import (
+	"github.com/DataDrake/eopkg-deps/index"
+	"github.com/DataDrake/eopkg-deps/index/indextest"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+// SyntheticSize is about the number of packages in a real repository
+const SyntheticSize = 10000
+
+// BenchmarkAll runs the benchmarks against every Store in Backends
+func BenchmarkAll(b *testing.B) {
+	for name, open := range Backends {
+		open := open
+		b.Run(name, func(b *testing.B) {
+			Benchmark(b, open)
+		})
+	}
+}
+
+// peakHeap runs fn while sampling the heap, reporting the most it grew by at any point as peak-MB
+func peakHeap(b *testing.B, fn func()) {
+	runtime.GC()
+	var stats runtime.MemStats
+	runtime.ReadMemStats(&stats)
+	base, peak := stats.HeapInuse, stats.HeapInuse
+	done := make(chan struct{})
+	sampled := make(chan struct{})
+	go func() {
+		defer close(sampled)
+		ticker := time.NewTicker(time.Millisecond)
+		defer ticker.Stop()
+		var stats runtime.MemStats
+		for {
+			runtime.ReadMemStats(&stats)
+			if stats.HeapInuse > peak {
+				peak = stats.HeapInuse
+			}
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+	fn()
+	close(done)
+	<-sampled
+	b.ReportMetric(float64(peak-base)/1e6, "peak-MB")
+}
+
+// Benchmark times the slowest queries of the Store made by open, on a synthetic index of SyntheticSize packages
+func Benchmark(b *testing.B, open Factory) {
+	i := indextest.RandomDAG(SyntheticSize, 1).Index()
+	b.Run("Update", func(b *testing.B) {
+		s := open(b)
+		b.ResetTimer()
+		for n := 0; n < b.N; n++ {
+			if err := s.Update(i); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	// Summaries in a few languages bring packages closer to the size of real ones
+	for n := range i.Packages {
+		for _, lang := range []string{"en", "de", "es", "fr", "it"} {
+			i.Packages[n].Summaries = append(i.Packages[n].Summaries, index.Summary{
+				Lang: lang,
+				Text: strings.Repeat(lang+" summary of "+i.Packages[n].Name+". ", 8),
+			})
+		}
+	}
+	path := filepath.Join(b.TempDir(), "eopkg-index.xml")
+	if err := i.Save(path); err != nil {
+		b.Fatal(err)
+	}
+	b.Run("UpdateLoaded", func(b *testing.B) {
+		s := open(b)
+		b.ResetTimer()
+		peakHeap(b, func() {
+			for n := 0; n < b.N; n++ {
+				loaded := index.NewIndex()
+				if err := loaded.Load(path); err != nil {
+					b.Fatal(err)
+				}
+				if err := s.Update(loaded); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	})
+	b.Run("UpdateStreamed", func(b *testing.B) {
+		s := open(b)
+		b.ResetTimer()
+		peakHeap(b, func() {
+			for n := 0; n < b.N; n++ {
+				if err := s.Update(index.File(path)); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	})
+	s := open(b)
+	if err := s.Update(i); err != nil {
+		b.Fatal(err)
+	}
+	root := indextest.Name(0)
+	b.Run("GetReverse", func(b *testing.B) {
+		for n := 0; n < b.N; n++ {
+			if _, err := s.GetReverse(root); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("WorstToDo", func(b *testing.B) {
+		for n := 0; n < b.N; n++ {
+			if _, err := s.WorstToDo(root, 0); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("WorstToDoExcluded", func(b *testing.B) {
+		exclude := []string{indextest.Name(1), indextest.Name(2), indextest.Name(3)}
+		for n := 0; n < b.N; n++ {
+			if _, err := s.WorstToDo(root, 0, exclude...); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("SetClosure", func(b *testing.B) {
+		for n := 0; n < b.N; n++ {
+			if err := s.SetClosure(true); err != nil {
+				b.Fatal(err)
+			}
+			if err := s.SetClosure(false); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	if err := s.SetClosure(true); err != nil {
+		b.Fatal(err)
+	}
+	b.Run("WorstToDoClosure", func(b *testing.B) {
+		for n := 0; n < b.N; n++ {
+			if _, err := s.WorstToDo(root, 0); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	if err := s.SetClosure(false); err != nil {
+		b.Fatal(err)
+	}
+	// Finishing the first packages queues a large part of the repository
+	var first []string
+	for n := 0; n < 20; n++ {
+		first = append(first, indextest.Name(n))
+	}
+	if err := s.StartToDo(first...); err != nil {
+		b.Fatal(err)
+	}
+	if err := s.DoneToDo(true, first...); err != nil {
+		b.Fatal(err)
+	}
+	b.Run("GetToDo", func(b *testing.B) {
+		for n := 0; n < b.N; n++ {
+			if _, _, _, err := s.GetToDo(); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("GetPending", func(b *testing.B) {
+		for n := 0; n < b.N; n++ {
+			if _, _, err := s.GetPending(); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
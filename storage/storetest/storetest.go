@@ -0,0 +1,952 @@
+//
+// Copyright 2018-2021 Bryan T. Meyers <root@datadrake.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+// Package storetest checks that every storage.Store behaves the same way.
+//
+// A test for a backend only needs to call Run with a Factory for it:
+//
+//	func TestSqlite(t *testing.T) {
+//	    storetest.Run(t, storetest.Sqlite)
+//	}
+//
+// Benchmark does the same for timing a backend on a repository the size of a real one.
+package storetest
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"errors"
+	"github.com/DataDrake/eopkg-deps/index"
+	"github.com/DataDrake/eopkg-deps/index/indextest"
+	"github.com/DataDrake/eopkg-deps/storage"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// Factory opens a new, empty Store which is closed when the test or benchmark finishes
+type Factory func(t testing.TB) storage.Store
+
+// Sqlite opens a SqliteStore in a temporary directory
+func Sqlite(t testing.TB) storage.Store {
+	s := storage.NewSqliteStore()
+	if err := s.Open(filepath.Join(t.TempDir(), "eopkg-deps.db")); err != nil {
+		t.Fatalf("Failed to open store, reason: '%s'", err)
+	}
+	t.Cleanup(func() { s.Close() })
+	return s
+}
+
+// Memory opens a MemoryStore
+func Memory(t testing.TB) storage.Store {
+	s := storage.NewMemoryStore()
+	if err := s.Open(""); err != nil {
+		t.Fatalf("Failed to open store, reason: '%s'", err)
+	}
+	t.Cleanup(func() { s.Close() })
+	return s
+}
+
+// Backends has a Factory for every Store
+var Backends = map[string]Factory{
+	"sqlite": Sqlite,
+	"memory": Memory,
+}
+
+// RunAll runs the conformance suite against every Store in Backends
+func RunAll(t *testing.T) {
+	for name, open := range Backends {
+		open := open
+		t.Run(name, func(t *testing.T) {
+			Run(t, open)
+		})
+	}
+	t.Run("sqliteShared", func(t *testing.T) {
+		location := filepath.Join(t.TempDir(), "eopkg-deps.db")
+		var stores []storage.Store
+		for i := 0; i < 4; i++ {
+			s := storage.NewSqliteStore()
+			if err := s.Open(location); err != nil {
+				t.Fatalf("Failed to open store, reason: '%s'", err)
+			}
+			t.Cleanup(func() { s.Close() })
+			stores = append(stores, s)
+		}
+		expectNil(t, "Update", stores[0].Update(Fixture()))
+		testParallel(t, stores...)
+		s := storage.NewSqliteStore()
+		expectNil(t, "OpenReadOnly", s.OpenReadOnly(location))
+		t.Cleanup(func() { s.Close() })
+		testReadOnly(t, s)
+	})
+	t.Run("memoryReadOnly", func(t *testing.T) {
+		s := storage.NewMemoryStore()
+		expectNil(t, "OpenReadOnly", s.OpenReadOnly(""))
+		testReadOnly(t, s)
+	})
+	// A dump from any backend has to restore into every other
+	for from, openFrom := range Backends {
+		for to, openTo := range Backends {
+			openFrom, openTo := openFrom, openTo
+			t.Run(from+"To"+to, func(t *testing.T) {
+				testPortable(t, openFrom(t), openTo(t))
+			})
+		}
+	}
+}
+
+// Run runs the conformance suite against the Store made by open
+func Run(t *testing.T, open Factory) {
+	tests := []struct {
+		name string
+		run  func(t *testing.T, s storage.Store)
+	}{
+		{"Packages", testPackages},
+		{"Forward", testForward},
+		{"Reverse", testReverse},
+		{"Provides", testProvides},
+		{"Graph", testGraph},
+		{"SetRelease", testSetRelease},
+		{"Rebuilt", testRebuilt},
+		{"ToDo", testToDo},
+		{"Impact", testImpact},
+		{"Atomic", testAtomic},
+		{"Durations", testDurations},
+		{"Worst", testWorst},
+		{"Closure", testClosure},
+		{"Stamp", testStamp},
+		{"Update", testUpdate},
+		{"Cancel", testCancel},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			s := open(t)
+			if err := s.Update(Fixture()); err != nil {
+				t.Fatalf("Failed to update, reason: '%s'", err)
+			}
+			test.run(t, s)
+		})
+	}
+	t.Run("Parallel", func(t *testing.T) {
+		s := open(t)
+		if err := s.Update(Fixture()); err != nil {
+			t.Fatalf("Failed to update, reason: '%s'", err)
+		}
+		testParallel(t, s)
+	})
+	t.Run("DumpRestore", func(t *testing.T) {
+		s := open(t)
+		if err := s.Update(Fixture()); err != nil {
+			t.Fatalf("Failed to update, reason: '%s'", err)
+		}
+		testPortable(t, s, open(t))
+	})
+	t.Run("Parse", func(t *testing.T) {
+		testParse(t, open(t), open(t))
+	})
+	t.Run("Lint", func(t *testing.T) {
+		testLint(t, open(t))
+	})
+	t.Run("RestoreInvalid", func(t *testing.T) {
+		s := open(t)
+		if err := s.Update(Fixture()); err != nil {
+			t.Fatalf("Failed to update, reason: '%s'", err)
+		}
+		testRestoreInvalid(t, s)
+	})
+}
+
+// fixture is a small index with a split -devel package, a -dbginfo package,
+// an unknown dependency and a cycle
+const fixture = `
+<PISI>
+    <Package>
+        <Name>zlib</Name>
+        <Summary xml:lang="en">Compression library</Summary>
+        <PartOf>system.base</PartOf>
+        <History><Update release="10"/><Update release="9"/></History>
+    </Package>
+    <Package>
+        <Name>zlib-devel</Name>
+        <PartOf>system.devel</PartOf>
+        <History><Update release="10"/></History>
+        <RuntimeDependencies><Dependency releaseFrom="10">zlib</Dependency></RuntimeDependencies>
+        <Provides><PkgConfig>zlib</PkgConfig></Provides>
+    </Package>
+    <Package>
+        <Name>openssl</Name>
+        <Summary xml:lang="en">Cryptography library</Summary>
+        <PartOf>system.base</PartOf>
+        <History><Update release="5"/></History>
+        <RuntimeDependencies><Dependency releaseFrom="9">zlib</Dependency></RuntimeDependencies>
+        <Provides><PkgConfig>libssl</PkgConfig><PkgConfig32>libssl</PkgConfig32></Provides>
+    </Package>
+    <Package>
+        <Name>curl</Name>
+        <Summary xml:lang="de">Datentransfer</Summary>
+        <Summary xml:lang="en">Transfer data with URLs</Summary>
+        <PartOf>network.util</PartOf>
+        <History><Update release="7"/></History>
+        <RuntimeDependencies>
+            <Dependency>openssl</Dependency>
+            <Dependency releaseFrom="10">zlib</Dependency>
+            <Dependency>not-in-index</Dependency>
+        </RuntimeDependencies>
+    </Package>
+    <Package>
+        <Name>git</Name>
+        <PartOf>programming.tools</PartOf>
+        <History><Update release="3"/></History>
+        <RuntimeDependencies><Dependency>curl</Dependency><Dependency>openssl</Dependency></RuntimeDependencies>
+    </Package>
+    <Package>
+        <Name>git-dbginfo</Name>
+        <PartOf>programming.tools</PartOf>
+        <History><Update release="3"/></History>
+        <RuntimeDependencies><Dependency>git</Dependency></RuntimeDependencies>
+    </Package>
+    <Package>
+        <Name>loop-a</Name>
+        <PartOf>desktop</PartOf>
+        <History><Update release="1"/></History>
+        <RuntimeDependencies><Dependency>loop-b</Dependency></RuntimeDependencies>
+    </Package>
+    <Package>
+        <Name>loop-b</Name>
+        <PartOf>desktop</PartOf>
+        <History><Update release="1"/></History>
+        <RuntimeDependencies><Dependency>loop-a</Dependency></RuntimeDependencies>
+    </Package>
+</PISI>
+`
+
+// Fixture gets the index every test in the suite starts from
+func Fixture() *index.Index {
+	i := index.NewIndex()
+	if err := xml.Unmarshal([]byte(fixture), i); err != nil {
+		panic(err.Error())
+	}
+	return i
+}
+
+// names gets the sorted names of packages
+func names(pkgs storage.Packages) []string {
+	list := make([]string, 0, len(pkgs))
+	for _, pkg := range pkgs {
+		list = append(list, pkg.Name)
+	}
+	sort.Strings(list)
+	return list
+}
+
+// expectNames fails the test unless pkgs has exactly the expected names
+func expectNames(t *testing.T, what string, pkgs storage.Packages, expected ...string) {
+	t.Helper()
+	if expected == nil {
+		expected = []string{}
+	}
+	sort.Strings(expected)
+	if actual := names(pkgs); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("%s: expected %v, found %v", what, expected, actual)
+	}
+}
+
+// expectError fails the test unless err wraps target
+func expectError(t *testing.T, what string, err, target error) {
+	t.Helper()
+	if !errors.Is(err, target) {
+		t.Errorf("%s: expected error '%v', found '%v'", what, target, err)
+	}
+}
+
+// expectNil fails the test if there is an error
+func expectNil(t *testing.T, what string, err error) {
+	t.Helper()
+	if err != nil {
+		t.Fatalf("%s: unexpected error '%s'", what, err)
+	}
+}
+
+// expectToDo fails the test unless the todo list has the expected counts and unblocked packages
+func expectToDo(t *testing.T, s storage.Store, count, done int, unblocked ...string) {
+	t.Helper()
+	pkgs, actualCount, actualDone, err := s.GetToDo()
+	expectNil(t, "GetToDo", err)
+	expectNames(t, "GetToDo", pkgs, unblocked...)
+	if actualCount != count || actualDone != done {
+		t.Errorf("GetToDo: expected %d remaining and %d done, found %d and %d", count, done, actualCount, actualDone)
+	}
+}
+
+func testPackages(t *testing.T, s storage.Store) {
+	pkgs, err := s.GetPackages()
+	expectNil(t, "GetPackages", err)
+	expectNames(t, "GetPackages", pkgs, "zlib", "openssl", "curl", "git", "loop-a", "loop-b")
+	pkg, err := s.GetPackage("curl")
+	expectNil(t, "GetPackage", err)
+	expected := storage.Package{Name: "curl", Release: 7, Summary: "Transfer data with URLs", Component: "network.util"}
+	if pkg != expected {
+		t.Errorf("GetPackage: expected %+v, found %+v", expected, pkg)
+	}
+	for _, name := range []string{"zlib-devel", "git-dbginfo", "not-in-index"} {
+		_, err = s.GetPackage(name)
+		expectError(t, "GetPackage", err, storage.ErrPackageNotFound)
+	}
+}
+
+func testForward(t *testing.T, s storage.Store) {
+	pkgs, err := s.GetForward("curl")
+	expectNil(t, "GetForward", err)
+	expectNames(t, "GetForward", pkgs, "openssl", "zlib")
+	for _, pkg := range pkgs {
+		if pkg.Name == "zlib" && (pkg.Release != 10 || pkg.Component != "system.base") {
+			t.Errorf("GetForward: expected release 10 in system.base, found %+v", pkg)
+		}
+		if (pkg.Name == "zlib" && pkg.Current != 10) || (pkg.Name == "openssl" && pkg.Current != 5) {
+			t.Errorf("GetForward: unexpected current release for %+v", pkg)
+		}
+	}
+	expectNil(t, "SetRelease", s.SetRelease("openssl", 6))
+	pkgs, err = s.GetForward("curl")
+	expectNil(t, "GetForward", err)
+	for _, pkg := range pkgs {
+		if pkg.Name == "openssl" && (pkg.Release != 0 || pkg.Current != 6) {
+			t.Errorf("GetForward: expected current release 6 after SetRelease, found %+v", pkg)
+		}
+	}
+	pkgs, err = s.GetForward("zlib")
+	expectNil(t, "GetForward", err)
+	expectNames(t, "GetForward", pkgs)
+	_, err = s.GetForward("zlib-devel")
+	expectError(t, "GetForward", err, storage.ErrPackageNotFound)
+}
+
+func testReverse(t *testing.T, s storage.Store) {
+	pkgs, err := s.GetReverse("zlib")
+	expectNil(t, "GetReverse", err)
+	expectNames(t, "GetReverse", pkgs, "curl", "openssl")
+	for _, pkg := range pkgs {
+		if pkg.Name == "openssl" && (pkg.Release != 9 || pkg.Current != 5) {
+			t.Errorf("GetReverse: expected release 9 and current release 5, found %+v", pkg)
+		}
+	}
+	pkgs, err = s.GetReverse("git")
+	expectNil(t, "GetReverse", err)
+	expectNames(t, "GetReverse", pkgs)
+	_, err = s.GetReverse("git-dbginfo")
+	expectError(t, "GetReverse", err, storage.ErrPackageNotFound)
+}
+
+func testProvides(t *testing.T, s storage.Store) {
+	provides, err := s.GetProvides("zlib")
+	expectNil(t, "GetProvides", err)
+	expected := storage.Provides{{Package: "zlib", Kind: storage.ProvidesPkgConfig, Name: "zlib"}}
+	if !reflect.DeepEqual(provides, expected) {
+		t.Errorf("GetProvides: expected %+v, found %+v", expected, provides)
+	}
+	provides, err = s.GetProvides("openssl")
+	expectNil(t, "GetProvides", err)
+	if len(provides) != 2 {
+		t.Errorf("GetProvides: expected 2 provides, found %+v", provides)
+	}
+	_, err = s.GetProvides("zlib-devel")
+	expectError(t, "GetProvides", err, storage.ErrPackageNotFound)
+	pkgs, err := s.WhatProvides("libssl")
+	expectNil(t, "WhatProvides", err)
+	expectNames(t, "WhatProvides", pkgs, "openssl")
+	_, err = s.WhatProvides("libcurl")
+	expectError(t, "WhatProvides", err, storage.ErrNotProvided)
+}
+
+func testGraph(t *testing.T, s storage.Store) {
+	pkgs, deps, err := s.GetGraph()
+	expectNil(t, "GetGraph", err)
+	if len(pkgs) != 6 {
+		t.Errorf("GetGraph: expected 6 packages, found %d", len(pkgs))
+	}
+	var edges []string
+	for _, dep := range deps {
+		edges = append(edges, dep.Left+"->"+dep.Right)
+	}
+	sort.Strings(edges)
+	expected := []string{
+		"curl->openssl", "curl->zlib", "git->curl", "git->openssl",
+		"loop-a->loop-b", "loop-b->loop-a", "openssl->zlib",
+	}
+	if !reflect.DeepEqual(edges, expected) {
+		t.Errorf("GetGraph: expected %v, found %v", expected, edges)
+	}
+}
+
+func testSetRelease(t *testing.T, s storage.Store) {
+	expectNil(t, "SetRelease", s.SetRelease("git", 4))
+	pkg, err := s.GetPackage("git")
+	expectNil(t, "GetPackage", err)
+	if pkg.Release != 4 {
+		t.Errorf("SetRelease: expected release 4, found %d", pkg.Release)
+	}
+	expectError(t, "SetRelease", s.SetRelease("not-in-index", 1), storage.ErrPackageNotFound)
+}
+
+func testToDo(t *testing.T, s storage.Store) {
+	expectToDo(t, s, 0, 0)
+	expectNil(t, "StartToDo", s.StartToDo("zlib"))
+	expectToDo(t, s, 1, 0, "zlib")
+	expectError(t, "StartToDo", s.StartToDo("zlib"), storage.ErrAlreadyStarted)
+	expectError(t, "ClaimToDo", s.ClaimToDo("git"), storage.ErrNotInToDo)
+	expectNil(t, "ClaimToDo", s.ClaimToDo("zlib"))
+	expectNil(t, "DoneToDo", s.DoneToDo(true, "zlib"))
+	// curl has to wait for openssl
+	expectToDo(t, s, 2, 1, "openssl")
+	expectError(t, "DoneToDo", s.DoneToDo(true, "zlib"), storage.ErrAlreadyDone)
+	expectError(t, "DoneToDo", s.DoneToDo(true, "git"), storage.ErrNotInToDo)
+	pkgs, deps, err := s.GetPending()
+	expectNil(t, "GetPending", err)
+	expectNames(t, "GetPending", pkgs, "curl", "openssl")
+	if len(deps) != 1 || deps[0].Left != "curl" || deps[0].Right != "openssl" {
+		t.Errorf("GetPending: expected curl->openssl, found %+v", deps)
+	}
+	expectNil(t, "DoneToDo", s.DoneToDo(false, "openssl"))
+	expectToDo(t, s, 1, 2, "curl")
+	expectNil(t, "DoneToDo", s.DoneToDo(true, "curl"))
+	expectToDo(t, s, 1, 3, "git")
+	expectNil(t, "ResetToDo", s.ResetToDo())
+	expectToDo(t, s, 0, 0)
+	// Packages in a cycle block each other forever
+	expectNil(t, "StartToDo", s.StartToDo("loop-a", "loop-b"))
+	expectToDo(t, s, 2, 0)
+}
+
+// expectImpact fails the test unless the unblocked packages have the expected impacts
+func expectImpact(t *testing.T, s storage.Store, expected map[string]int) {
+	t.Helper()
+	pkgs, _, _, err := s.GetToDo()
+	expectNil(t, "GetToDo", err)
+	actual := make(map[string]int)
+	for _, pkg := range pkgs {
+		actual[pkg.Name] = pkg.Impact
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("GetToDo: expected impacts %v, found %v", expected, actual)
+	}
+}
+
+func testImpact(t *testing.T, s storage.Store) {
+	expectNil(t, "StartToDo", s.StartToDo("zlib", "openssl", "curl", "git"))
+	expectImpact(t, s, map[string]int{"zlib": 3})
+	expectNil(t, "DoneToDo", s.DoneToDo(false, "zlib"))
+	expectImpact(t, s, map[string]int{"openssl": 2})
+	expectNil(t, "ResetToDo", s.ResetToDo())
+	// git depends on zlib through packages which are not pending
+	expectNil(t, "StartToDo", s.StartToDo("zlib", "git"))
+	expectImpact(t, s, map[string]int{"zlib": 1, "git": 0})
+	expectNil(t, "ResetToDo", s.ResetToDo())
+}
+
+func testAtomic(t *testing.T, s storage.Store) {
+	err := s.StartToDo("zlib", "not-in-index", "also-not-in-index")
+	expectError(t, "StartToDo", err, storage.ErrPackageNotFound)
+	var missing *storage.MissingError
+	if !errors.As(err, &missing) || !reflect.DeepEqual(missing.Names, []string{"not-in-index", "also-not-in-index"}) {
+		t.Errorf("StartToDo: expected both missing packages, found '%v'", err)
+	}
+	expectToDo(t, s, 0, 0)
+	expectNil(t, "StartToDo", s.StartToDo("zlib", "openssl"))
+	expectError(t, "DoneToDo", s.DoneToDo(true, "zlib", "git"), storage.ErrNotInToDo)
+	expectToDo(t, s, 2, 0, "zlib")
+	expectError(t, "ClaimToDo", s.ClaimToDo("zlib", "git"), storage.ErrNotInToDo)
+}
+
+func testDurations(t *testing.T, s storage.Store) {
+	expectNil(t, "SetDurations", s.SetDurations(storage.Packages{{Name: "curl", Duration: 60}, {Name: "git", Duration: 30}}))
+	expectNil(t, "SetDurations", s.SetDurations(storage.Packages{{Name: "git", Duration: 90}}))
+	expectNil(t, "StartToDo", s.StartToDo("zlib"))
+	expectNil(t, "DoneToDo", s.DoneToDo(false, "zlib"))
+	pkgs, err := s.GetDurations()
+	expectNil(t, "GetDurations", err)
+	expectNames(t, "GetDurations", pkgs, "curl", "git", "zlib")
+	for _, pkg := range pkgs {
+		if (pkg.Name == "curl" && pkg.Duration != 60) || (pkg.Name == "git" && pkg.Duration != 90) || pkg.Duration < 0 {
+			t.Errorf("GetDurations: unexpected duration for %+v", pkg)
+		}
+	}
+	err = s.SetDurations(storage.Packages{{Name: "curl", Duration: 120}, {Name: "git", Duration: -1}})
+	expectError(t, "SetDurations", err, storage.ErrNegativeDuration)
+	pkgs, err = s.GetDurations()
+	expectNil(t, "GetDurations", err)
+	for _, pkg := range pkgs {
+		if pkg.Name == "curl" && pkg.Duration != 60 {
+			t.Errorf("SetDurations: a rejected batch changed the duration of 'curl' to %d", pkg.Duration)
+		}
+	}
+}
+
+func testWorst(t *testing.T, s storage.Store) {
+	depths := func(pkgs storage.Packages) map[string]int {
+		found := make(map[string]int)
+		for _, pkg := range pkgs {
+			found[pkg.Name] = pkg.Depth
+		}
+		return found
+	}
+	tests := []struct {
+		name     string
+		maxDepth int
+		exclude  []string
+		expected map[string]int
+	}{
+		{"zlib", 0, nil, map[string]int{"openssl": 1, "curl": 1, "git": 2}},
+		{"zlib", 1, nil, map[string]int{"openssl": 1, "curl": 1}},
+		{"zlib", 0, []string{"curl"}, map[string]int{"openssl": 1, "git": 2}},
+		{"zlib", 0, []string{"curl", "openssl"}, map[string]int{}},
+		{"git", 0, nil, map[string]int{}},
+		{"loop-a", 0, nil, map[string]int{"loop-b": 1, "loop-a": 2}},
+	}
+	for _, test := range tests {
+		pkgs, err := s.WorstToDo(test.name, test.maxDepth, test.exclude...)
+		expectNil(t, "WorstToDo", err)
+		if actual := depths(pkgs); !reflect.DeepEqual(actual, test.expected) {
+			t.Errorf("WorstToDo(%s, %d, %v): expected %v, found %v", test.name, test.maxDepth, test.exclude, test.expected, actual)
+		}
+	}
+	_, err := s.WorstToDo("not-in-index", 0)
+	expectError(t, "WorstToDo", err, storage.ErrPackageNotFound)
+}
+
+// expectClosure fails the test unless the reverse closure of every package is the same
+// whether it is read from the closure or traversed again
+func expectClosure(t *testing.T, s storage.Store, what string) {
+	t.Helper()
+	pkgs, err := s.GetPackages()
+	expectNil(t, "GetPackages", err)
+	for _, pkg := range pkgs {
+		kept, err := s.WorstToDo(pkg.Name, 0)
+		expectNil(t, "WorstToDo", err)
+		// Excluding any package at all means traversing again
+		traversed, err := s.WorstToDo(pkg.Name, 0, "not-in-index")
+		expectNil(t, "WorstToDo", err)
+		sort.Sort(kept)
+		sort.Sort(traversed)
+		if !reflect.DeepEqual(kept, traversed) {
+			t.Errorf("%s: closure of %s is %v, expected %v", what, pkg.Name, kept, traversed)
+		}
+	}
+}
+
+func testClosure(t *testing.T, s storage.Store) {
+	expectNil(t, "SetClosure", s.SetClosure(true))
+	expectNil(t, "SetClosure", s.SetClosure(true))
+	testWorst(t, s)
+	testImpact(t, s)
+	// The closure follows the index through every rebuild, whatever changed
+	i := indextest.RandomCyclic(100, 10, 1).Index()
+	expectNil(t, "Update", s.Update(i))
+	expectClosure(t, s, "Update")
+	d, err := s.Dump()
+	expectNil(t, "Dump", err)
+	i.Packages[20].RuntimeDependencies = nil
+	i.Packages = append(i.Packages[:30], i.Packages[31:]...)
+	i.Packages[40].RuntimeDependencies = append(i.Packages[40].RuntimeDependencies, index.Dependency{Name: "extra"})
+	i.Packages = append(i.Packages, index.Package{
+		Name:                "extra",
+		Releases:            []index.Update{{Number: 1}},
+		RuntimeDependencies: []index.Dependency{{Name: i.Packages[50].Name}, {Name: i.Packages[60].Name}},
+	})
+	expectNil(t, "Update", s.Update(i))
+	expectClosure(t, s, "Update with changed dependencies")
+	expectNil(t, "Update", s.Update(indextest.RandomCyclic(100, 10, 2).Index()))
+	expectClosure(t, s, "Update with another index")
+	expectNil(t, "Restore", s.Restore(d))
+	expectClosure(t, s, "Restore")
+	expectNil(t, "Update", s.Update(Fixture()))
+	testWorst(t, s)
+	d, err = s.Dump()
+	expectNil(t, "Dump", err)
+	expectNil(t, "Restore", s.Restore(&storage.Dump{Version: storage.DumpVersion}))
+	_, err = s.WorstToDo("zlib", 0)
+	expectError(t, "WorstToDo", err, storage.ErrPackageNotFound)
+	expectNil(t, "Restore", s.Restore(d))
+	testWorst(t, s)
+	expectNil(t, "SetClosure", s.SetClosure(false))
+	testWorst(t, s)
+}
+
+// testParse checks that updating from a saved index gives the same store as updating from the Index
+func testParse(t *testing.T, parsed, direct storage.Store) {
+	i := indextest.RandomCyclic(200, 10, 1).
+		Package("dep-&-escapes").Summary("en", "Needs <escaping> & \"quotes\"").PkgConfig("escapes").
+		Package("dep-user").DepFrom("dep-&-escapes", 2).Deps("not-in-index").
+		Index()
+	path := filepath.Join(t.TempDir(), "eopkg-index.xml")
+	expectNil(t, "Save", i.Save(path))
+	expectNil(t, "Update", parsed.Update(index.File(path)))
+	expectNil(t, "Update", direct.Update(i))
+	expected, err := direct.Dump()
+	expectNil(t, "Dump", err)
+	actual, err := parsed.Dump()
+	expectNil(t, "Dump", err)
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Update: parsed index differs from the one written")
+	}
+}
+
+// testLint checks that whatever Lint reports as an error is left out by Update, rather than crashing it
+func testLint(t *testing.T, s storage.Store) {
+	i := indextest.New().
+		Package("zlib").Release(3).Summary("en", "First").
+		Package("openssl").Release(2).Deps("zlib", "openssl").
+		Package("zlib").Release(4).Summary("en", "Second").
+		Package("curl").Release().Deps("zlib").
+		Package("git").DepFrom("zlib", 4).DepFrom("openssl", 2).Deps("curl", "not-in-index").
+		Index()
+	if count := i.Lint().Count(index.Error); count != 2 {
+		t.Fatalf("Lint: expected 2 errors, found %d", count)
+	}
+	expectNil(t, "Update", s.Update(i))
+	pkgs, err := s.GetPackages()
+	expectNil(t, "GetPackages", err)
+	expectNames(t, "GetPackages", pkgs, "zlib", "openssl", "git")
+	pkg, err := s.GetPackage("zlib")
+	expectNil(t, "GetPackage", err)
+	if pkg.Release != 3 || pkg.Summary != "First" {
+		t.Errorf("Update: expected the first zlib, found %+v", pkg)
+	}
+	pkgs, err = s.GetReverse("zlib")
+	expectNil(t, "GetReverse", err)
+	expectNames(t, "GetReverse", pkgs, "openssl", "git")
+}
+
+// sameStamp checks if two stamps are for the same contents, whatever time zone they were read in
+func sameStamp(a, b index.Stamp) bool {
+	return a.Path == b.Path && a.ModTime.Equal(b.ModTime) && a.Hash == b.Hash
+}
+
+// expectStamp checks the Stamp recorded in the store
+func expectStamp(t *testing.T, what string, s storage.Store, expected index.Stamp) {
+	t.Helper()
+	stamp, err := s.GetStamp()
+	expectNil(t, "GetStamp", err)
+	if !sameStamp(stamp, expected) {
+		t.Errorf("%s: expected %+v recorded, found %+v", what, expected, stamp)
+	}
+}
+
+func testStamp(t *testing.T, s storage.Store) {
+	expectStamp(t, "GetStamp", s, index.Stamp{})
+	f := index.File(filepath.Join(t.TempDir(), "eopkg-index.xml"))
+	expectNil(t, "Save", Fixture().Save(string(f)))
+	expected, err := f.Stamp()
+	expectNil(t, "Stamp", err)
+	expectNil(t, "SetStamp", s.SetStamp(expected))
+	expectStamp(t, "SetStamp", s, expected)
+	// Neither an Update nor a Restore comes from the recorded file any more
+	expectNil(t, "Update", s.Update(Fixture()))
+	expectStamp(t, "Update", s, index.Stamp{})
+	expectNil(t, "SetStamp", s.SetStamp(expected))
+	d, err := s.Dump()
+	expectNil(t, "Dump", err)
+	expectNil(t, "Restore", s.Restore(d))
+	expectStamp(t, "Restore", s, index.Stamp{})
+}
+
+// second gets the error from a method which also reports something else
+func second(_ bool, err error) error {
+	return err
+}
+
+// expectRebuilt checks if Rebuilt found the package pending, and that its release was recorded either way
+func expectRebuilt(t *testing.T, s storage.Store, name string, release int, expected bool) {
+	t.Helper()
+	queued, err := s.Rebuilt(name, release)
+	expectNil(t, "Rebuilt", err)
+	if queued != expected {
+		t.Errorf("Rebuilt(%s): expected pending %t, found %t", name, expected, queued)
+	}
+	pkg, err := s.GetPackage(name)
+	expectNil(t, "GetPackage", err)
+	if pkg.Release != release {
+		t.Errorf("Rebuilt(%s): expected release %d, found %d", name, release, pkg.Release)
+	}
+}
+
+func testRebuilt(t *testing.T, s storage.Store) {
+	expectNil(t, "StartToDo", s.StartToDo("zlib"))
+	expectRebuilt(t, s, "zlib", 11, true)
+	expectToDo(t, s, 2, 1, "openssl")
+	// Once done, or never queued, only the release changes
+	expectRebuilt(t, s, "zlib", 12, false)
+	expectRebuilt(t, s, "git", 4, false)
+	expectToDo(t, s, 2, 1, "openssl")
+	_, err := s.Rebuilt("not-in-index", 1)
+	expectError(t, "Rebuilt", err, storage.ErrPackageNotFound)
+	expectToDo(t, s, 2, 1, "openssl")
+}
+
+func testUpdate(t *testing.T, s storage.Store) {
+	expectNil(t, "SetDurations", s.SetDurations(storage.Packages{{Name: "curl", Duration: 60}}))
+	expectNil(t, "StartToDo", s.StartToDo("zlib"))
+	expectNil(t, "SetRelease", s.SetRelease("git", 4))
+	expectNil(t, "Update", s.Update(Fixture()))
+	// The todo list goes, but recorded durations stay
+	expectToDo(t, s, 0, 0)
+	pkgs, err := s.GetDurations()
+	expectNil(t, "GetDurations", err)
+	expectNames(t, "GetDurations", pkgs, "curl")
+	pkg, err := s.GetPackage("git")
+	expectNil(t, "GetPackage", err)
+	if pkg.Release != 3 {
+		t.Errorf("Update: expected release 3, found %d", pkg.Release)
+	}
+}
+
+func testCancel(t *testing.T, s storage.Store) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := s.GetPackagesContext(ctx)
+	expectError(t, "GetPackagesContext", err, context.Canceled)
+	_, err = s.WorstToDoContext(ctx, "zlib", 0)
+	expectError(t, "WorstToDoContext", err, context.Canceled)
+	expectError(t, "StartToDoContext", s.StartToDoContext(ctx, "zlib"), context.Canceled)
+	expectToDo(t, s, 0, 0)
+	expectError(t, "UpdateContext", s.UpdateContext(ctx, index.NewIndex()), context.Canceled)
+	pkgs, err := s.GetPackages()
+	expectNil(t, "GetPackages", err)
+	if len(pkgs) != 6 {
+		t.Errorf("UpdateContext: expected the old packages to remain, found %d", len(pkgs))
+	}
+}
+
+// normalize sorts everything in a dump, since stores do not have to keep things in order
+func normalize(d *storage.Dump) {
+	sort.Sort(d.Packages)
+	sort.Slice(d.Dependencies, func(i, j int) bool {
+		a, b := d.Dependencies[i], d.Dependencies[j]
+		if a.Left != b.Left {
+			return a.Left < b.Left
+		}
+		return a.Right < b.Right
+	})
+	sort.Slice(d.Provides, func(i, j int) bool {
+		a, b := d.Provides[i], d.Provides[j]
+		if a.Package != b.Package {
+			return a.Package < b.Package
+		}
+		if a.Name != b.Name {
+			return a.Name < b.Name
+		}
+		return a.Kind < b.Kind
+	})
+	sort.Slice(d.ToDo, func(i, j int) bool {
+		return d.ToDo[i].Name < d.ToDo[j].Name
+	})
+	sort.Slice(d.Timing, func(i, j int) bool {
+		return d.Timing[i].Name < d.Timing[j].Name
+	})
+}
+
+// testPortable moves a store with a todo list and history from one store to another through a dump
+func testPortable(t *testing.T, from, to storage.Store) {
+	if err := from.Update(Fixture()); err != nil {
+		t.Fatalf("Failed to update, reason: '%s'", err)
+	}
+	expectNil(t, "SetDurations", from.SetDurations(storage.Packages{{Name: "curl", Duration: 60}}))
+	expectNil(t, "StartToDo", from.StartToDo("zlib", "loop-a"))
+	expectNil(t, "DoneToDo", from.DoneToDo(true, "zlib"))
+	expectNil(t, "SetRelease", from.SetRelease("zlib", 11))
+	expected, err := from.Dump()
+	expectNil(t, "Dump", err)
+	var b bytes.Buffer
+	expectNil(t, "Write", expected.Write(&b))
+	d, err := storage.ReadDump(&b)
+	expectNil(t, "ReadDump", err)
+	expectNil(t, "Restore", to.Restore(d))
+	actual, err := to.Dump()
+	expectNil(t, "Dump", err)
+	normalize(expected)
+	normalize(actual)
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Restore: expected %+v, found %+v", expected, actual)
+	}
+	expectToDo(t, to, 3, 1, "openssl", "loop-a")
+	pkgs, err := to.WorstToDo("zlib", 0)
+	expectNil(t, "WorstToDo", err)
+	expectNames(t, "WorstToDo", pkgs, "openssl", "curl", "git")
+}
+
+func testRestoreInvalid(t *testing.T, s storage.Store) {
+	valid, err := s.Dump()
+	expectNil(t, "Dump", err)
+	tests := []struct {
+		name  string
+		spoil func(d *storage.Dump)
+	}{
+		{"version", func(d *storage.Dump) { d.Version++ }},
+		{"duplicate", func(d *storage.Dump) { d.Packages = append(d.Packages, d.Packages[0]) }},
+		{"dependency", func(d *storage.Dump) {
+			d.Dependencies = append(d.Dependencies, storage.Dependency{Left: "zlib", Right: "not-in-index"})
+		}},
+		{"provides", func(d *storage.Dump) {
+			d.Provides = append(d.Provides, storage.Provide{Package: "zlib", Kind: "cmake", Name: "ZLIB"})
+		}},
+		{"todo", func(d *storage.Dump) {
+			d.ToDo = append(d.ToDo, storage.ToDo{Name: "zlib"}, storage.ToDo{Name: "zlib"})
+		}},
+	}
+	for _, test := range tests {
+		d := *valid
+		d.Packages = append(storage.Packages(nil), valid.Packages...)
+		d.Dependencies = append(storage.Dependencies(nil), valid.Dependencies...)
+		d.Provides = append(storage.Provides(nil), valid.Provides...)
+		d.ToDo = append([]storage.ToDo(nil), valid.ToDo...)
+		test.spoil(&d)
+		expectError(t, "Restore("+test.name+")", s.Restore(&d), storage.ErrInvalidDump)
+	}
+	_, err = storage.ReadDump(strings.NewReader(`{"version": 1, "packages": [], "builds": []}`))
+	expectError(t, "ReadDump", err, storage.ErrInvalidDump)
+	// Nothing is lost by refusing a dump
+	pkgs, err := s.GetPackages()
+	expectNil(t, "GetPackages", err)
+	if len(pkgs) != 6 {
+		t.Errorf("Restore: expected the old packages to remain, found %d", len(pkgs))
+	}
+}
+
+// allowed checks if an error is nil or one of the expected ones
+func allowed(err error, expected ...error) bool {
+	if err == nil {
+		return true
+	}
+	for _, target := range expected {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// testParallel hammers the todo list from many goroutines at once, spread across the stores,
+// which must all share the same contents
+func testParallel(t *testing.T, stores ...storage.Store) {
+	const workers = 8
+	const rounds = 40
+	all := []string{"zlib", "openssl", "curl", "git", "loop-a", "loop-b"}
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for r := 0; r < rounds; r++ {
+				s := stores[(w+r)%len(stores)]
+				name := all[(w*r+r)%len(all)]
+				var err error
+				switch r % 5 {
+				case 0:
+					err = s.StartToDo(name)
+				case 1:
+					err = s.ClaimToDo(name)
+				case 2:
+					err = s.DoneToDo(w%2 == 0, name)
+				case 3:
+					_, _, _, err = s.GetToDo()
+				case 4:
+					_, err = s.WorstToDo(name, 0)
+				}
+				if !allowed(err, storage.ErrAlreadyStarted, storage.ErrNotInToDo, storage.ErrAlreadyDone) {
+					t.Errorf("Worker %d: unexpected error '%s'", w, err)
+				}
+			}
+		}(w)
+	}
+	wg.Wait()
+	d, err := stores[0].Dump()
+	expectNil(t, "Dump", err)
+	expectNil(t, "Check", d.Check())
+	pending := 0
+	for _, item := range d.ToDo {
+		if !item.Done {
+			pending++
+		}
+	}
+	_, count, _, err := stores[0].GetToDo()
+	expectNil(t, "GetToDo", err)
+	if count != pending {
+		t.Errorf("GetToDo: expected %d remaining, found %d", pending, count)
+	}
+	// Exactly one of many identical changes may succeed
+	expectNil(t, "ResetToDo", stores[0].ResetToDo())
+	race := func(what string, change func(s storage.Store) error) {
+		var lock sync.Mutex
+		succeeded := 0
+		var wg sync.WaitGroup
+		for w := 0; w < workers; w++ {
+			wg.Add(1)
+			go func(s storage.Store) {
+				defer wg.Done()
+				err := change(s)
+				if !allowed(err, storage.ErrAlreadyStarted, storage.ErrAlreadyDone) {
+					t.Errorf("%s: unexpected error '%s'", what, err)
+				}
+				if err == nil {
+					lock.Lock()
+					succeeded++
+					lock.Unlock()
+				}
+			}(stores[w%len(stores)])
+		}
+		wg.Wait()
+		if succeeded != 1 {
+			t.Errorf("%s: expected exactly one success, found %d", what, succeeded)
+		}
+	}
+	race("StartToDo", func(s storage.Store) error { return s.StartToDo("zlib") })
+	race("DoneToDo", func(s storage.Store) error { return s.DoneToDo(true, "zlib") })
+	expectToDo(t, stores[0], 2, 1, "openssl")
+}
+
+// testReadOnly makes sure that a read-only store can be queried but refuses every change
+func testReadOnly(t *testing.T, s storage.Store) {
+	_, err := s.GetPackages()
+	expectNil(t, "GetPackages", err)
+	_, _, _, err = s.GetToDo()
+	expectNil(t, "GetToDo", err)
+	_, err = s.Dump()
+	expectNil(t, "Dump", err)
+	// WorstToDo may need to write temporary tables, even when the store is read-only
+	if _, err = s.WorstToDo("zlib", 0); !allowed(err, storage.ErrPackageNotFound) {
+		t.Errorf("WorstToDo: unexpected error '%s'", err)
+	}
+	changes := map[string]error{
+		"SetRelease":   s.SetRelease("zlib", 1),
+		"StartToDo":    s.StartToDo("zlib"),
+		"ClaimToDo":    s.ClaimToDo("zlib"),
+		"DoneToDo":     s.DoneToDo(true, "zlib"),
+		"SetDurations": s.SetDurations(storage.Packages{{Name: "zlib", Duration: 1}}),
+		"ResetToDo":    s.ResetToDo(),
+		"Update":       s.Update(Fixture()),
+		"Restore":      s.Restore(&storage.Dump{Version: storage.DumpVersion}),
+		"SetClosure":   s.SetClosure(true),
+		"SetStamp":     s.SetStamp(index.Stamp{Path: "eopkg-index.xml"}),
+		"Rebuilt":      second(s.Rebuilt("zlib", 11)),
+	}
+	for what, err := range changes {
+		expectError(t, what, err, storage.ErrReadOnly)
+	}
+}